@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/fishworks/gofish"
+	"github.com/urfave/cli"
+
+	"github.com/gofish-bot/gofish-bot/audit"
+	"github.com/gofish-bot/gofish-bot/gofishgithub"
+	"github.com/gofish-bot/gofish-bot/log"
+	"github.com/gofish-bot/gofish-bot/printer"
+)
+
+func auditCommand(newGoFish func(ctx context.Context) *gofishgithub.GoFish) cli.Command {
+	var apply bool
+	var parallel int
+
+	return cli.Command{
+		Name:  "audit",
+		Usage: "Audit the foods already in fish-food",
+		Subcommands: []cli.Command{
+			{
+				Name:      "checksums",
+				Usage:     "Download every package, bypassing the cache, and compare it to the recorded sha256",
+				ArgsUsage: "[food...]",
+				Flags: []cli.Flag{
+					cli.BoolFlag{
+						Name:        "apply, a",
+						Usage:       "Open an issue in fish-food listing mismatches and dead urls",
+						Destination: &apply,
+					}, cli.IntFlag{
+						Name:        "parallel",
+						Usage:       "Number of packages downloaded at the same time",
+						Value:       4,
+						Destination: &parallel,
+					},
+				},
+				Action: func(c *cli.Context) error {
+					ctx := context.Background()
+					goFish := newGoFish(ctx)
+					defer goFish.Workspace.Close()
+					defer goFish.Cache.Close()
+
+					names := []string(c.Args())
+					if len(names) == 0 {
+						var err error
+						names, err = goFish.ListFoods(ctx)
+						if err != nil {
+							return err
+						}
+					}
+
+					foods := []*gofish.Food{}
+					for _, name := range names {
+						food, err := goFish.GetFood(ctx, name)
+						if err != nil {
+							log.G(ctx).Warnf("Could not read food %s: %v", name, err)
+							continue
+						}
+						if food.Name == "" {
+							food.Name = name
+						}
+						foods = append(foods, food)
+					}
+
+					results := audit.Checksums(ctx, goFish.HTTPClient, foods, parallel)
+					printer.AuditResults(results)
+
+					problems := audit.Problems(results)
+					if len(problems) == 0 || !apply {
+						return nil
+					}
+					return goFish.CreateIssue(ctx, auditIssueTitle(problems), audit.IssueBody(problems))
+				},
+			},
+		},
+	}
+}
+
+func auditIssueTitle(problems []audit.Result) string {
+	seen := map[string]bool{}
+	foods := []string{}
+	for _, p := range problems {
+		if !seen[p.Food] {
+			seen[p.Food] = true
+			foods = append(foods, p.Food)
+		}
+	}
+	sort.Strings(foods)
+	return fmt.Sprintf("Checksum mismatches or dead urls: %s", strings.Join(foods, ", "))
+}
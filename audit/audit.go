@@ -0,0 +1,130 @@
+package audit
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/fishworks/gofish"
+
+	"github.com/gofish-bot/gofish-bot/download"
+	"github.com/gofish-bot/gofish-bot/log"
+)
+
+// Statuses of an audited package. A package is only dead when the server
+// reports it gone, other failures, like rate limits, leave it unknown.
+const (
+	StatusOK       = "ok"
+	StatusMismatch = "mismatch"
+	StatusDead     = "dead"
+	StatusError    = "error"
+)
+
+// Result is the outcome of auditing one package of a food
+type Result struct {
+	Food     string
+	Version  string
+	OS       string
+	Arch     string
+	URL      string
+	Expected string
+	Actual   string
+	Status   string
+	Error    string
+}
+
+// Checksums downloads every package of the foods, bypassing the cache, and
+// compares it to the recorded sha256. Up to parallel packages are downloaded
+// at the same time.
+func Checksums(ctx context.Context, client *http.Client, foods []*gofish.Food, parallel int) []Result {
+	if parallel < 1 {
+		parallel = 1
+	}
+
+	results := []Result{}
+	for _, food := range foods {
+		for _, pkg := range food.Packages {
+			results = append(results, Result{
+				Food:     food.Name,
+				Version:  food.Version,
+				OS:       pkg.OS,
+				Arch:     pkg.Arch,
+				URL:      pkg.URL,
+				Expected: pkg.SHA256,
+			})
+		}
+	}
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < parallel; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				check(ctx, client, &results[j])
+			}
+		}()
+	}
+	for j := range results {
+		jobs <- j
+	}
+	close(jobs)
+	wg.Wait()
+
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Food < results[j].Food
+	})
+	return results
+}
+
+func check(ctx context.Context, client *http.Client, result *Result) {
+	log.G(ctx).Debugf("Auditing %s %s/%s: %s", result.Food, result.OS, result.Arch, result.URL)
+
+	sha, err := download.SHA256(ctx, client, result.URL)
+	if err != nil {
+		result.Error = err.Error()
+		result.Status = StatusError
+		if statusErr, ok := err.(*download.StatusError); ok && (statusErr.StatusCode == http.StatusNotFound || statusErr.StatusCode == http.StatusGone) {
+			result.Status = StatusDead
+		}
+		return
+	}
+
+	result.Actual = sha
+	result.Status = StatusOK
+	if !strings.EqualFold(sha, result.Expected) {
+		result.Status = StatusMismatch
+	}
+}
+
+// Problems returns the results that are not ok
+func Problems(results []Result) []Result {
+	problems := []Result{}
+	for _, result := range results {
+		if result.Status != StatusOK {
+			problems = append(problems, result)
+		}
+	}
+	return problems
+}
+
+// IssueBody renders the problems as a markdown issue
+func IssueBody(problems []Result) string {
+	b := strings.Builder{}
+	b.WriteString("The following packages no longer match the sha256 recorded in their food, ")
+	b.WriteString("or could not be downloaded. Installing them fails for every user.\n\n")
+	b.WriteString("| Food | Version | OS/Arch | Status | Details |\n")
+	b.WriteString("|------|---------|---------|--------|---------|\n")
+	for _, p := range problems {
+		details := p.Error
+		if p.Status == StatusMismatch {
+			details = fmt.Sprintf("recorded `%s`, downloaded `%s`", p.Expected, p.Actual)
+		}
+		fmt.Fprintf(&b, "| %s | %s | [%s/%s](%s) | %s | %s |\n", p.Food, p.Version, p.OS, p.Arch, p.URL, p.Status, strings.Replace(details, "|", "\\|", -1))
+	}
+	return b.String()
+}
@@ -0,0 +1,91 @@
+package audit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/fishworks/gofish"
+)
+
+func TestChecksums(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ok.tar.gz", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("content"))
+	})
+	mux.HandleFunc("/reuploaded.tar.gz", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("other content"))
+	})
+	mux.HandleFunc("/deleted.tar.gz", func(w http.ResponseWriter, r *http.Request) {
+		http.NotFound(w, r)
+	})
+	mux.HandleFunc("/removed.tar.gz", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "Gone", http.StatusGone)
+	})
+	mux.HandleFunc("/forbidden.tar.gz", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+	})
+	mux.HandleFunc("/broken.tar.gz", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "Bad Gateway", http.StatusBadGateway)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	sha := "ed7002b439e9ac845f22357d822bac1444730fbdb6016d3ec9432297b9ec9f73"
+	foods := []*gofish.Food{
+		{
+			Name:    "tool",
+			Version: "1.0.0",
+			Packages: []*gofish.Package{
+				{OS: "darwin", Arch: "amd64", URL: server.URL + "/ok.tar.gz", SHA256: sha},
+				{OS: "linux", Arch: "amd64", URL: server.URL + "/reuploaded.tar.gz", SHA256: sha},
+				{OS: "windows", Arch: "amd64", URL: server.URL + "/deleted.tar.gz", SHA256: sha},
+			},
+		},
+		{
+			Name:    "other",
+			Version: "2.0.0",
+			Packages: []*gofish.Package{
+				{OS: "linux", Arch: "amd64", URL: server.URL + "/broken.tar.gz", SHA256: sha},
+				{OS: "darwin", Arch: "amd64", URL: server.URL + "/ok.tar.gz", SHA256: strings.ToUpper(sha)},
+				{OS: "windows", Arch: "amd64", URL: server.URL + "/forbidden.tar.gz", SHA256: sha},
+				{OS: "windows", Arch: "arm64", URL: server.URL + "/removed.tar.gz", SHA256: sha},
+			},
+		},
+	}
+
+	results := Checksums(context.Background(), server.Client(), foods, 2)
+
+	want := []struct {
+		food   string
+		os     string
+		status string
+	}{
+		{"other", "linux", StatusError},
+		{"other", "darwin", StatusOK},
+		{"other", "windows", StatusError},
+		{"other", "windows", StatusDead},
+		{"tool", "darwin", StatusOK},
+		{"tool", "linux", StatusMismatch},
+		{"tool", "windows", StatusDead},
+	}
+	if len(results) != len(want) {
+		t.Fatalf("Checksums() returned %d results, want %d", len(results), len(want))
+	}
+	for i, w := range want {
+		if results[i].Food != w.food || results[i].OS != w.os || results[i].Status != w.status {
+			t.Errorf("Checksums()[%d] = %s %s %s, want %s %s %s", i, results[i].Food, results[i].OS, results[i].Status, w.food, w.os, w.status)
+		}
+	}
+
+	problems := Problems(results)
+	if len(problems) != 5 {
+		t.Errorf("Problems() returned %d results, want 5", len(problems))
+	}
+	body := IssueBody(problems)
+	if !strings.Contains(body, "| tool | 1.0.0 |") || !strings.Contains(body, "| mismatch |") {
+		t.Errorf("IssueBody() = %s", body)
+	}
+}
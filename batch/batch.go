@@ -0,0 +1,72 @@
+// Package batch groups food updates into shared pull requests
+package batch
+
+import (
+	"fmt"
+
+	"github.com/gofish-bot/gofish-bot/models"
+)
+
+// Grouping of the updates
+const (
+	GroupNone  = ""
+	GroupAll   = "all"
+	GroupOrg   = "org"
+	GroupLabel = "label"
+)
+
+// Batch is a group of applications updated in one pull request
+type Batch struct {
+	Name         string
+	Applications []*models.Application
+}
+
+// Group splits the applications into batches of at most maxSize, grouped by
+// all, org or label. Applications without a label are not batched when
+// grouping by label, and every application gets its own batch when group is
+// empty. A maxSize of 0 is unlimited. Batches are ordered by their first
+// application.
+func Group(applications []*models.Application, group string, maxSize int) ([]Batch, error) {
+	keys := []string{}
+	groups := map[string][]*models.Application{}
+	for _, app := range applications {
+		var key string
+		switch group {
+		case GroupNone:
+			key = app.Name
+		case GroupAll:
+			key = GroupAll
+		case GroupOrg:
+			key = app.Organization
+		case GroupLabel:
+			key = app.Label
+			if key == "" {
+				key = app.Name
+			}
+		default:
+			return nil, fmt.Errorf("unknown batch grouping '%s', expected all, org or label", group)
+		}
+		if _, ok := groups[key]; !ok {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], app)
+	}
+
+	batches := []Batch{}
+	for _, key := range keys {
+		apps := groups[key]
+		for i := 0; len(apps) > 0; i++ {
+			n := len(apps)
+			if maxSize > 0 && n > maxSize {
+				n = maxSize
+			}
+			name := key
+			if i > 0 {
+				name = fmt.Sprintf("%s-%d", key, i+1)
+			}
+			batches = append(batches, Batch{Name: name, Applications: apps[:n]})
+			apps = apps[n:]
+		}
+	}
+	return batches, nil
+}
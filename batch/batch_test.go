@@ -0,0 +1,98 @@
+package batch
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/gofish-bot/gofish-bot/models"
+)
+
+func TestGroup(t *testing.T) {
+	apps := []*models.Application{
+		{Name: "kubectl", Organization: "kubernetes", Label: "k8s"},
+		{Name: "helm", Organization: "helm", Label: "k8s"},
+		{Name: "kind", Organization: "kubernetes-sigs", Label: "k8s"},
+		{Name: "gh", Organization: "cli"},
+		{Name: "kubeadm", Organization: "kubernetes"},
+	}
+
+	tests := []struct {
+		name    string
+		group   string
+		maxSize int
+		want    map[string][]string
+		order   []string
+		wantErr bool
+	}{
+		{
+			name:  "none",
+			group: GroupNone,
+			order: []string{"kubectl", "helm", "kind", "gh", "kubeadm"},
+			want: map[string][]string{
+				"kubectl": {"kubectl"}, "helm": {"helm"}, "kind": {"kind"}, "gh": {"gh"}, "kubeadm": {"kubeadm"},
+			},
+		},
+		{
+			name:  "all",
+			group: GroupAll,
+			order: []string{"all"},
+			want:  map[string][]string{"all": {"kubectl", "helm", "kind", "gh", "kubeadm"}},
+		},
+		{
+			name:    "all with max size",
+			group:   GroupAll,
+			maxSize: 2,
+			order:   []string{"all", "all-2", "all-3"},
+			want: map[string][]string{
+				"all": {"kubectl", "helm"}, "all-2": {"kind", "gh"}, "all-3": {"kubeadm"},
+			},
+		},
+		{
+			name:  "org",
+			group: GroupOrg,
+			order: []string{"kubernetes", "helm", "kubernetes-sigs", "cli"},
+			want: map[string][]string{
+				"kubernetes": {"kubectl", "kubeadm"}, "helm": {"helm"}, "kubernetes-sigs": {"kind"}, "cli": {"gh"},
+			},
+		},
+		{
+			name:  "label",
+			group: GroupLabel,
+			order: []string{"k8s", "gh", "kubeadm"},
+			want: map[string][]string{
+				"k8s": {"kubectl", "helm", "kind"}, "gh": {"gh"}, "kubeadm": {"kubeadm"},
+			},
+		},
+		{
+			name:    "unknown",
+			group:   "repo",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			batches, err := Group(apps, tt.group, tt.maxSize)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Group() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			order := []string{}
+			got := map[string][]string{}
+			for _, b := range batches {
+				order = append(order, b.Name)
+				for _, app := range b.Applications {
+					got[b.Name] = append(got[b.Name], app.Name)
+				}
+			}
+			if !reflect.DeepEqual(order, tt.order) {
+				t.Errorf("Group() batches = %v, want %v", order, tt.order)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Group() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"errors"
+
+	"github.com/dustin/go-humanize"
+	"github.com/urfave/cli"
+
+	"github.com/gofish-bot/gofish-bot/cache"
+	"github.com/gofish-bot/gofish-bot/log"
+	"github.com/gofish-bot/gofish-bot/models"
+	"github.com/gofish-bot/gofish-bot/printer"
+)
+
+const defaultCacheDir = "/tmp/gofish-bot"
+
+func cacheCommand(cacheDir, cacheMaxSize *string) cli.Command {
+	return cli.Command{
+		Name:  "cache",
+		Usage: "Manage the download cache",
+		Subcommands: []cli.Command{
+			{
+				Name:  "ls",
+				Usage: "List cached downloads, most recently used first",
+				Action: func(c *cli.Context) error {
+					downloadCache := getCache(getSettings("config/settings.yaml"), *cacheDir, *cacheMaxSize)
+					entries, err := downloadCache.List()
+					if err != nil {
+						return err
+					}
+					printer.CacheEntries(entries)
+					return nil
+				},
+			},
+			{
+				Name:      "prune",
+				Usage:     "Evict the least recently used downloads until the cache fits the maximum size",
+				ArgsUsage: "[size]",
+				Action: func(c *cli.Context) error {
+					downloadCache := getCache(getSettings("config/settings.yaml"), *cacheDir, *cacheMaxSize)
+					maxSize := downloadCache.MaxSize
+					if c.NArg() > 0 {
+						size, err := humanize.ParseBytes(c.Args().First())
+						if err != nil {
+							return err
+						}
+						maxSize = int64(size)
+					}
+					if maxSize <= 0 {
+						return errors.New("no maximum cache size, pass the size or set --cache-max-size or cache_max_size")
+					}
+					evicted, err := downloadCache.Prune(maxSize)
+					if err != nil {
+						return err
+					}
+					log.L.Infof("Evicted %d downloads", len(evicted))
+					return nil
+				},
+			},
+		},
+	}
+}
+
+// getCache opens the download cache. Flags take precedence over the settings.
+func getCache(settings models.Settings, cacheDir, cacheMaxSize string) *cache.Cache {
+	if cacheDir == "" {
+		cacheDir = settings.CacheDir
+	}
+	if cacheDir == "" {
+		cacheDir = defaultCacheDir
+	}
+	if cacheMaxSize == "" {
+		cacheMaxSize = settings.CacheMaxSize
+	}
+
+	var maxSize uint64
+	if cacheMaxSize != "" {
+		var err error
+		maxSize, err = humanize.ParseBytes(cacheMaxSize)
+		if err != nil {
+			log.L.Fatalf("Invalid cache max size '%s': %v", cacheMaxSize, err)
+		}
+	}
+
+	downloadCache, err := cache.New(cacheDir, int64(maxSize))
+	if err != nil {
+		log.L.Fatalf("Error creating download cache: %v", err)
+	}
+	return downloadCache
+}
@@ -0,0 +1,353 @@
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofish-bot/gofish-bot/log"
+)
+
+// Cache is a content addressed download cache. Downloads are indexed by their
+// URL and stored by the sha256 of their content, so the same file is only
+// stored once and can be verified on reuse.
+//
+// Files returned by Get and Put are in use until their entry is closed and
+// are never evicted before.
+type Cache struct {
+	Dir string
+	// MaxSize is the maximum size in bytes of all cached files, least
+	// recently used files are evicted when it is exceeded. 0 means unlimited.
+	MaxSize int64
+
+	mu sync.Mutex
+	// inUse counts the open entries per file
+	inUse map[string]int
+}
+
+// Entry is a cached download
+type Entry struct {
+	URL      string
+	SHA256   string
+	Size     int64
+	LastUsed time.Time
+	// Path is the location of the cached content
+	Path string `json:"-"`
+
+	// cache is set while the entry is open
+	cache *Cache
+}
+
+// Close releases the cached file of the entry, so it can be evicted. It is
+// safe to call Close more than once.
+func (e *Entry) Close() error {
+	if e == nil || e.cache == nil {
+		return nil
+	}
+	c := e.cache
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e.cache = nil
+	c.release(e.Path)
+	if c.inUse[e.Path] > 0 || c.MaxSize <= 0 {
+		return nil
+	}
+	_, err := c.prune(c.MaxSize)
+	return err
+}
+
+// New creates the cache directories in dir
+func New(dir string, maxSize int64) (*Cache, error) {
+	c := &Cache{Dir: dir, MaxSize: maxSize, inUse: map[string]int{}}
+	for _, d := range []string{c.blobDir(), c.indexDir(), c.tmpDir()} {
+		if err := os.MkdirAll(d, os.ModePerm); err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+func (c *Cache) blobDir() string  { return filepath.Join(c.Dir, "blobs") }
+func (c *Cache) indexDir() string { return filepath.Join(c.Dir, "index") }
+func (c *Cache) tmpDir() string   { return filepath.Join(c.Dir, "tmp") }
+
+func (c *Cache) indexPath(url string) string {
+	return filepath.Join(c.indexDir(), fmt.Sprintf("%x.json", sha256.Sum256([]byte(url))))
+}
+
+func (c *Cache) blobPath(sha, url string) string {
+	return filepath.Join(c.blobDir(), sha+getExtension(url))
+}
+
+// Get returns the cached download of url, which must be closed when it is
+// no longer read. The content is verified against its sha256, corrupt
+// entries are removed and reported as missing.
+func (c *Cache) Get(url string) (*Entry, bool) {
+	c.mu.Lock()
+	entry, err := c.readIndex(c.indexPath(url))
+	if err != nil {
+		c.mu.Unlock()
+		return nil, false
+	}
+	// The file is in use while it is hashed, so it is not evicted meanwhile
+	c.acquire(entry)
+	c.mu.Unlock()
+
+	sha, err := hashFile(entry.Path)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err != nil || sha != entry.SHA256 {
+		entry.cache = nil
+		c.release(entry.Path)
+		log.L.Warnf("Removing corrupt cache entry for %s", url)
+		os.Remove(c.indexPath(url))
+		if c.inUse[entry.Path] == 0 {
+			os.Remove(entry.Path)
+		}
+		return nil, false
+	}
+
+	entry.LastUsed = time.Now()
+	if err := c.writeIndex(entry); err != nil {
+		log.L.Debugf("Could not update cache index for %s: %v", url, err)
+	}
+	return entry, true
+}
+
+// Put stores the content read from r as the download of url and returns its
+// entry, which must be closed when it is no longer read. The content is
+// written to a temporary file and only moved into the cache when complete.
+// If size is not negative, content of any other size is rejected.
+func (c *Cache) Put(url string, r io.Reader, size int64) (*Entry, error) {
+	tmp, err := ioutil.TempFile(c.tmpDir(), "download-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmp.Name())
+
+	h := sha256.New()
+	written, err := io.Copy(io.MultiWriter(tmp, h), r)
+	if err != nil {
+		tmp.Close()
+		return nil, err
+	}
+	if err := tmp.Close(); err != nil {
+		return nil, err
+	}
+	if size >= 0 && written != size {
+		return nil, fmt.Errorf("incomplete content: got %d of %d bytes", written, size)
+	}
+
+	entry := &Entry{
+		URL:      url,
+		SHA256:   fmt.Sprintf("%x", h.Sum(nil)),
+		Size:     written,
+		LastUsed: time.Now(),
+	}
+	entry.Path = c.blobPath(entry.SHA256, url)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := os.Rename(tmp.Name(), entry.Path); err != nil {
+		return nil, err
+	}
+	if err := c.writeIndex(entry); err != nil {
+		return nil, err
+	}
+	c.acquire(entry)
+
+	if c.MaxSize > 0 {
+		if _, err := c.prune(c.MaxSize); err != nil {
+			log.L.Warnf("Could not prune cache: %v", err)
+		}
+	}
+	return entry, nil
+}
+
+// Open opens the cached content of the entry
+func (c *Cache) Open(entry *Entry) (*os.File, error) {
+	return os.Open(entry.Path)
+}
+
+// List returns all entries, most recently used first
+func (c *Cache) List() ([]*Entry, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.list()
+}
+
+// Close evicts the least recently used entries exceeding MaxSize. It is
+// called at the end of a run, entries that are still open are kept.
+func (c *Cache) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.MaxSize <= 0 {
+		return nil
+	}
+	_, err := c.prune(c.MaxSize)
+	return err
+}
+
+// Prune evicts the least recently used entries until the cached files take
+// up at most maxSize bytes, and returns the evicted entries. Files in use are
+// never evicted.
+func (c *Cache) Prune(maxSize int64) ([]*Entry, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.prune(maxSize)
+}
+
+// Clear removes everything from the cache
+func (c *Cache) Clear() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.inUse = map[string]int{}
+	for _, d := range []string{c.blobDir(), c.indexDir(), c.tmpDir()} {
+		if err := os.RemoveAll(d); err != nil {
+			return err
+		}
+		if err := os.MkdirAll(d, os.ModePerm); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Cache) list() ([]*Entry, error) {
+	files, err := ioutil.ReadDir(c.indexDir())
+	if err != nil {
+		return nil, err
+	}
+
+	entries := []*Entry{}
+	for _, f := range files {
+		entry, err := c.readIndex(filepath.Join(c.indexDir(), f.Name()))
+		if err != nil {
+			log.L.Debugf("Skipping cache index %s: %v", f.Name(), err)
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].LastUsed.After(entries[j].LastUsed)
+	})
+	return entries, nil
+}
+
+func (c *Cache) prune(maxSize int64) ([]*Entry, error) {
+	entries, err := c.list()
+	if err != nil {
+		return nil, err
+	}
+
+	// Files are shared between urls with the same content
+	refs := map[string]int{}
+	var total int64
+	for _, entry := range entries {
+		if refs[entry.Path] == 0 {
+			total += entry.Size
+		}
+		refs[entry.Path]++
+	}
+
+	evicted := []*Entry{}
+	for i := len(entries) - 1; i >= 0 && total > maxSize; i-- {
+		entry := entries[i]
+		if c.inUse[entry.Path] > 0 {
+			continue
+		}
+		log.L.Debugf("Evicting %s from cache", entry.URL)
+		if err := os.Remove(c.indexPath(entry.URL)); err != nil && !os.IsNotExist(err) {
+			return evicted, err
+		}
+		refs[entry.Path]--
+		if refs[entry.Path] == 0 {
+			if err := os.Remove(entry.Path); err != nil && !os.IsNotExist(err) {
+				return evicted, err
+			}
+			total -= entry.Size
+		}
+		evicted = append(evicted, entry)
+	}
+	return evicted, nil
+}
+
+// acquire marks the file of the entry in use until the entry is closed
+func (c *Cache) acquire(entry *Entry) {
+	entry.cache = c
+	c.inUse[entry.Path]++
+}
+
+func (c *Cache) release(path string) {
+	c.inUse[path]--
+	if c.inUse[path] <= 0 {
+		delete(c.inUse, path)
+	}
+}
+
+func (c *Cache) readIndex(path string) (*Entry, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	entry := &Entry{}
+	if err := json.Unmarshal(b, entry); err != nil {
+		return nil, err
+	}
+	entry.Path = c.blobPath(entry.SHA256, entry.URL)
+	return entry, nil
+}
+
+// writeIndex atomically replaces the index file of the entry
+func (c *Cache) writeIndex(entry *Entry) error {
+	b, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(c.tmpDir(), "index-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), c.indexPath(entry.URL))
+}
+
+func hashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%x", h.Sum(nil)), nil
+}
+
+// From github.com/fishworks/gofish@v0.13.0/food.go
+func getExtension(path string) string {
+	urlParts := strings.Split(path, "/")
+	parts := strings.Split(urlParts[len(urlParts)-1], ".")
+	if len(parts) < 2 {
+		return filepath.Ext(path)
+	}
+	return "." + strings.Join([]string{parts[len(parts)-2], parts[len(parts)-1]}, ".")
+}
@@ -0,0 +1,199 @@
+package cache
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newCache(t *testing.T, maxSize int64) *Cache {
+	dir, err := ioutil.TempDir("", "gofish-bot-cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	c, err := New(dir, maxSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestCache_PutGet(t *testing.T) {
+	c := newCache(t, 0)
+
+	url := "https://github.com/org/tool/releases/download/v1.0.0/tool_linux_amd64.tar.gz"
+	entry, err := c.Put(url, strings.NewReader("content"), -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry.SHA256 != "ed7002b439e9ac845f22357d822bac1444730fbdb6016d3ec9432297b9ec9f73" {
+		t.Errorf("Put() sha256 = %s", entry.SHA256)
+	}
+	if !strings.HasSuffix(entry.Path, ".tar.gz") {
+		t.Errorf("Put() path %s does not keep the extension", entry.Path)
+	}
+
+	got, ok := c.Get(url)
+	if !ok {
+		t.Fatalf("Get() missed %s", url)
+	}
+	b, _ := ioutil.ReadFile(got.Path)
+	if string(b) != "content" {
+		t.Errorf("Get() content = %s", b)
+	}
+
+	if _, ok := c.Get(url + ".sig"); ok {
+		t.Errorf("Get() found an url never put")
+	}
+}
+
+func TestCache_PutIncomplete(t *testing.T) {
+	c := newCache(t, 0)
+
+	url := "https://example.com/tool"
+	if _, err := c.Put(url, strings.NewReader("cont"), int64(len("content"))); err == nil {
+		t.Errorf("Put() accepted incomplete content")
+	}
+	if _, ok := c.Get(url); ok {
+		t.Errorf("Get() returned incomplete content")
+	}
+	files, _ := ioutil.ReadDir(c.tmpDir())
+	if len(files) != 0 {
+		t.Errorf("Put() left %d temporary files", len(files))
+	}
+}
+
+func TestCache_GetCorrupt(t *testing.T) {
+	c := newCache(t, 0)
+
+	url := "https://example.com/tool"
+	entry, err := c.Put(url, strings.NewReader("content"), -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry.Close()
+	if err := ioutil.WriteFile(entry.Path, []byte("<html>404</html>"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := c.Get(url); ok {
+		t.Errorf("Get() returned a corrupt entry")
+	}
+	if _, err := os.Stat(entry.Path); !os.IsNotExist(err) {
+		t.Errorf("Get() did not remove the corrupt file")
+	}
+}
+
+func TestCache_Prune(t *testing.T) {
+	c := newCache(t, 0)
+
+	for _, url := range []string{"https://example.com/a", "https://example.com/b", "https://example.com/c"} {
+		entry, err := c.Put(url, strings.NewReader(url), -1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		entry.Close()
+		time.Sleep(10 * time.Millisecond)
+	}
+	// a is now the most recently used
+	entry, _ := c.Get("https://example.com/a")
+	entry.Close()
+
+	evicted, err := c.Prune(int64(2 * len("https://example.com/a")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(evicted) != 1 || evicted[0].URL != "https://example.com/b" {
+		t.Errorf("Prune() evicted %v, want b", evicted)
+	}
+
+	entries, _ := c.List()
+	if len(entries) != 2 || entries[0].URL != "https://example.com/a" {
+		t.Errorf("List() = %v", entries)
+	}
+}
+
+func TestCache_MaxSize(t *testing.T) {
+	c := newCache(t, 10)
+
+	a, _ := c.Put("https://example.com/a", strings.NewReader("12345678"), -1)
+	// a is no longer in use once it is closed
+	a.Close()
+	time.Sleep(10 * time.Millisecond)
+	b, _ := c.Put("https://example.com/b", strings.NewReader("87654321"), -1)
+	b.Close()
+
+	if _, ok := c.Get("https://example.com/a"); ok {
+		t.Errorf("Put() did not evict the least recently used entry")
+	}
+	if _, ok := c.Get("https://example.com/b"); !ok {
+		t.Errorf("Put() evicted the new entry")
+	}
+}
+
+func TestCache_MaxSizeInUse(t *testing.T) {
+	c := newCache(t, 10)
+
+	// An entry larger than the cache is kept until it is closed
+	large, err := c.Put("https://example.com/large", strings.NewReader("0123456789abcdef"), -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(large.Path); err != nil {
+		t.Fatalf("Put() evicted the new entry: %v", err)
+	}
+	f, err := c.Open(large)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	f.Close()
+
+	// Entries in use are not evicted by other downloads
+	time.Sleep(10 * time.Millisecond)
+	small, err := c.Put("https://example.com/small", strings.NewReader("1234"), -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range []*Entry{large, small} {
+		if _, err := os.Stat(entry.Path); err != nil {
+			t.Errorf("Put() evicted %s in use: %v", entry.URL, err)
+		}
+	}
+
+	// Closing the last entry of a file enforces the limit during the run
+	if err := large.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(large.Path); !os.IsNotExist(err) {
+		t.Errorf("Close() did not evict %s", large.URL)
+	}
+	if _, err := os.Stat(small.Path); err != nil {
+		t.Errorf("Close() evicted %s in use: %v", small.URL, err)
+	}
+	// Closing twice does not release the file of another entry
+	large.Close()
+	if _, err := os.Stat(small.Path); err != nil {
+		t.Errorf("Close() evicted %s in use: %v", small.URL, err)
+	}
+}
+
+func TestCache_SharedFile(t *testing.T) {
+	c := newCache(t, 1)
+
+	// Entries of the same download share the file, which is in use while
+	// either entry is open
+	a, _ := c.Put("https://example.com/a/tool", strings.NewReader("content"), -1)
+	b, _ := c.Get("https://example.com/a/tool")
+	a.Close()
+	if _, err := os.Stat(b.Path); err != nil {
+		t.Fatalf("Close() evicted the file of an open entry: %v", err)
+	}
+	b.Close()
+	if _, err := os.Stat(b.Path); !os.IsNotExist(err) {
+		t.Errorf("Close() did not evict the released file")
+	}
+}
@@ -0,0 +1,193 @@
+package checksum
+
+import (
+	"encoding/json"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Algorithm is the hash algorithm of a published checksum
+type Algorithm string
+
+const (
+	SHA256 Algorithm = "sha256"
+	SHA512 Algorithm = "sha512"
+)
+
+// Entry is a single checksum published for a file
+type Entry struct {
+	File      string
+	Algorithm Algorithm
+	Sum       string
+}
+
+var (
+	bsdRe = regexp.MustCompile(`^(SHA256|SHA512|SHA2-256|SHA2-512)\s*\((.+)\)\s*=\s*([0-9a-fA-F]+)$`)
+	gnuRe = regexp.MustCompile(`^\\?([0-9a-fA-F]{64}|[0-9a-fA-F]{128})(?:\s+[ *]?(.+))?$`)
+	hexRe = regexp.MustCompile(`^[0-9a-fA-F]{64}$|^[0-9a-fA-F]{128}$`)
+)
+
+var sidecarSuffixes = []string{".sha256sum", ".sha256", ".sha512sum", ".sha512"}
+
+// attachmentSuffixes are the extensions of signatures, certificates and
+// attestations published next to checksum files, like checksums.txt.sig
+var attachmentSuffixes = []string{
+	".sig", ".asc", ".gpg", ".minisig", ".pem", ".crt", ".cert", ".bundle",
+	".sigstore", ".sigstore.json", ".intoto.jsonl", ".att", ".sbom", ".sbom.json",
+	".spdx", ".spdx.json", ".cdx.json",
+}
+
+// IsChecksumFile reports whether a release asset contains checksums of
+// other release assets
+func IsChecksumFile(name string) bool {
+	clean := strings.ToLower(name)
+	for _, suffix := range attachmentSuffixes {
+		if strings.HasSuffix(clean, suffix) {
+			return false
+		}
+	}
+	if sidecarTarget(clean) != "" {
+		return true
+	}
+	for _, fragment := range []string{"checksum", "sha256sums", "sha512sums", "shasums"} {
+		if strings.Contains(clean, fragment) {
+			return true
+		}
+	}
+	return strings.HasSuffix(clean, ".json") && strings.Contains(clean, "sha")
+}
+
+// sidecarTarget returns the name of the file a per asset checksum file
+// belongs to, or an empty string
+func sidecarTarget(name string) string {
+	for _, suffix := range sidecarSuffixes {
+		if strings.HasSuffix(strings.ToLower(name), suffix) {
+			return name[:len(name)-len(suffix)]
+		}
+	}
+	return ""
+}
+
+// Parse reads the checksums in the content of the checksum file name. It
+// supports GNU coreutils output in text and binary mode, BSD style tagged
+// lines, per asset sidecar files containing only the hash, and JSON
+// manifests.
+func Parse(name string, content []byte) []Entry {
+	trimmed := strings.TrimSpace(string(content))
+	if strings.HasPrefix(trimmed, "{") || strings.HasPrefix(trimmed, "[") {
+		var manifest interface{}
+		if err := json.Unmarshal([]byte(trimmed), &manifest); err == nil {
+			entries := parseJSON(manifest)
+			sort.Slice(entries, func(i, j int) bool {
+				if entries[i].File == entries[j].File {
+					return entries[i].Algorithm < entries[j].Algorithm
+				}
+				return entries[i].File < entries[j].File
+			})
+			return entries
+		}
+	}
+
+	entries := []Entry{}
+	target := sidecarTarget(name)
+
+	for _, line := range strings.Split(trimmed, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if m := bsdRe.FindStringSubmatch(line); m != nil {
+			entries = appendEntry(entries, m[2], m[3])
+			continue
+		}
+
+		if m := gnuRe.FindStringSubmatch(line); m != nil {
+			file := m[2]
+			if file == "" {
+				file = target
+			}
+			entries = appendEntry(entries, file, m[1])
+		}
+	}
+	return entries
+}
+
+// parseJSON walks a JSON manifest looking for objects mapping file names to
+// hashes, or objects with a name and a hash field
+func parseJSON(node interface{}) []Entry {
+	entries := []Entry{}
+
+	switch n := node.(type) {
+	case []interface{}:
+		for _, child := range n {
+			entries = append(entries, parseJSON(child)...)
+		}
+	case map[string]interface{}:
+		file := firstString(n, "name", "file", "filename", "path", "asset")
+		for _, key := range []string{"sha256", "sha512", "checksum", "digest", "hash"} {
+			if sum, ok := n[key].(string); ok && file != "" {
+				entries = appendEntry(entries, file, sum)
+			}
+		}
+		for key, value := range n {
+			switch v := value.(type) {
+			case string:
+				if hexRe.MatchString(v) && file == "" {
+					entries = appendEntry(entries, key, v)
+				}
+			default:
+				entries = append(entries, parseJSON(v)...)
+			}
+		}
+	}
+	return entries
+}
+
+func firstString(n map[string]interface{}, keys ...string) string {
+	for _, key := range keys {
+		if s, ok := n[key].(string); ok {
+			return s
+		}
+	}
+	return ""
+}
+
+func appendEntry(entries []Entry, file, sum string) []Entry {
+	sum = strings.ToLower(strings.TrimSpace(sum))
+	sum = strings.TrimPrefix(strings.TrimPrefix(sum, "sha256:"), "sha512:")
+
+	var algorithm Algorithm
+	switch len(sum) {
+	case 64:
+		algorithm = SHA256
+	case 128:
+		algorithm = SHA512
+	default:
+		return entries
+	}
+	if !hexRe.MatchString(sum) {
+		return entries
+	}
+
+	file = strings.TrimSpace(file)
+	file = strings.TrimPrefix(file, "*")
+	file = path.Base(strings.ReplaceAll(file, "\\", "/"))
+	if file == "" || file == "." || file == "/" {
+		return entries
+	}
+
+	return append(entries, Entry{File: file, Algorithm: algorithm, Sum: sum})
+}
+
+// Lookup finds the checksum of the file, matching the file name exactly
+func Lookup(entries []Entry, file string, algorithm Algorithm) (string, bool) {
+	for _, entry := range entries {
+		if entry.File == file && entry.Algorithm == algorithm {
+			return entry.Sum, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,143 @@
+package checksum
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var (
+	sha256A = strings.Repeat("a", 64)
+	sha256B = strings.Repeat("b", 64)
+	sha512A = strings.Repeat("c", 128)
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		want    []Entry
+	}{
+		{
+			name:    "GNU coreutils text mode",
+			file:    "checksums.txt",
+			content: sha256A + "  tool_linux_amd64.tar.gz\n" + sha256B + "  tool_darwin_amd64.tar.gz\n",
+			want: []Entry{
+				{File: "tool_linux_amd64.tar.gz", Algorithm: SHA256, Sum: sha256A},
+				{File: "tool_darwin_amd64.tar.gz", Algorithm: SHA256, Sum: sha256B},
+			},
+		},
+		{
+			name:    "GNU coreutils binary mode",
+			file:    "SHA256SUMS",
+			content: sha256A + " *tool_linux_amd64.tar.gz\n",
+			want:    []Entry{{File: "tool_linux_amd64.tar.gz", Algorithm: SHA256, Sum: sha256A}},
+		},
+		{
+			name:    "paths are reduced to the file name",
+			file:    "checksums.txt",
+			content: sha256A + "  ./dist/tool_linux_amd64.tar.gz\n",
+			want:    []Entry{{File: "tool_linux_amd64.tar.gz", Algorithm: SHA256, Sum: sha256A}},
+		},
+		{
+			name:    "BSD style",
+			file:    "checksums.txt",
+			content: "SHA256 (tool_linux_amd64.tar.gz) = " + sha256A + "\nSHA512 (tool_linux_amd64.tar.gz) = " + sha512A + "\n",
+			want: []Entry{
+				{File: "tool_linux_amd64.tar.gz", Algorithm: SHA256, Sum: sha256A},
+				{File: "tool_linux_amd64.tar.gz", Algorithm: SHA512, Sum: sha512A},
+			},
+		},
+		{
+			name:    "sidecar with only the hash",
+			file:    "tool_linux_amd64.tar.gz.sha256",
+			content: strings.ToUpper(sha256A) + "\n",
+			want:    []Entry{{File: "tool_linux_amd64.tar.gz", Algorithm: SHA256, Sum: sha256A}},
+		},
+		{
+			name:    "sha256sum sidecar with file name",
+			file:    "tool_linux_amd64.tar.gz.sha256sum",
+			content: sha256A + "  tool_linux_amd64.tar.gz",
+			want:    []Entry{{File: "tool_linux_amd64.tar.gz", Algorithm: SHA256, Sum: sha256A}},
+		},
+		{
+			name:    "SHA-512 sidecar",
+			file:    "tool_linux_amd64.tar.gz.sha512",
+			content: sha512A,
+			want:    []Entry{{File: "tool_linux_amd64.tar.gz", Algorithm: SHA512, Sum: sha512A}},
+		},
+		{
+			name:    "JSON map",
+			file:    "checksums.json",
+			content: `{"tool_linux_amd64.tar.gz": "` + sha256A + `", "tool_darwin_amd64.tar.gz": "` + sha256B + `"}`,
+			want: []Entry{
+				{File: "tool_darwin_amd64.tar.gz", Algorithm: SHA256, Sum: sha256B},
+				{File: "tool_linux_amd64.tar.gz", Algorithm: SHA256, Sum: sha256A},
+			},
+		},
+		{
+			name:    "JSON artifact list",
+			file:    "artifacts-sha.json",
+			content: `{"artifacts": [{"name": "tool_linux_amd64.tar.gz", "digest": "sha256:` + sha256A + `", "size": 1234}]}`,
+			want:    []Entry{{File: "tool_linux_amd64.tar.gz", Algorithm: SHA256, Sum: sha256A}},
+		},
+		{
+			name:    "ignores comments and garbage",
+			file:    "checksums.txt",
+			content: "# generated\nnot a checksum line\n1234  short.tar.gz\n",
+			want:    []Entry{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Parse(tt.file, []byte(tt.content)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Parse() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLookup(t *testing.T) {
+	entries := []Entry{
+		{File: "tool-debug", Algorithm: SHA256, Sum: sha256B},
+		{File: "tool", Algorithm: SHA256, Sum: sha256A},
+		{File: "tool.sig", Algorithm: SHA256, Sum: sha256B},
+	}
+
+	sum, ok := Lookup(entries, "tool", SHA256)
+	if !ok || sum != sha256A {
+		t.Errorf("Lookup() = %v, %v, want %v", sum, ok, sha256A)
+	}
+	if _, ok := Lookup(entries, "tool", SHA512); ok {
+		t.Errorf("Lookup() found a sha512 for tool")
+	}
+}
+
+func TestIsChecksumFile(t *testing.T) {
+	tests := map[string]bool{
+		"checksums.txt":                  true,
+		"tool_0.1.0_checksums.txt":       true,
+		"SHA256SUMS":                     true,
+		"tool_linux_amd64.tar.gz.sha256": true,
+		"tool.sha512sum":                 true,
+		"sha256sums.json":                true,
+		"tool_linux_amd64.tar.gz":        false,
+		"tool_linux_amd64.tar.gz.sig":    false,
+		"release.json":                   false,
+		"checksums.txt.sig":              false,
+		"checksums.txt.pem":              false,
+		"checksums.txt.asc":              false,
+		"checksums.txt.minisig":          false,
+		"checksums.txt.sigstore.json":    false,
+		"checksums.txt.intoto.jsonl":     false,
+		"SHA256SUMS.gpg":                 false,
+		"tool.tar.gz.sha256.sig":         false,
+		"tool_checksums.sbom.json":       false,
+	}
+	for name, want := range tests {
+		if got := IsChecksumFile(name); got != want {
+			t.Errorf("IsChecksumFile(%s) = %v, want %v", name, got, want)
+		}
+	}
+}
@@ -2,18 +2,27 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"io/ioutil"
 	"net/url"
 	"os"
 	"path"
 	"strings"
+	"time"
 
+	"github.com/dustin/go-humanize"
 	"github.com/fishworks/gofish/pkg/home"
+	"github.com/gofish-bot/gofish-bot/cache"
 	"github.com/gofish-bot/gofish-bot/gofishgithub"
+	"github.com/gofish-bot/gofish-bot/httpclient"
+	"github.com/gofish-bot/gofish-bot/lint"
 
 	"github.com/gofish-bot/gofish-bot/log"
 	"github.com/gofish-bot/gofish-bot/models"
+	"github.com/gofish-bot/gofish-bot/printer"
+	"github.com/gofish-bot/gofish-bot/publish"
 	"github.com/gofish-bot/gofish-bot/strategy/github"
+	"github.com/gofish-bot/gofish-bot/workspace"
 
 	"github.com/sirupsen/logrus"
 
@@ -24,29 +33,33 @@ import (
 	"github.com/urfave/cli"
 )
 
-const tmpDir = "/tmp/gofish-bot"
-
-func init() {
-	mkDir(tmpDir)
-}
-
-func mkDir(path string) {
-	err := os.MkdirAll(tmpDir, os.ModePerm)
-	if err == nil || os.IsExist(err) {
-		return
-	} else {
-		panic(err)
-	}
-}
-
 func main() {
 	var clean bool
 	var verbose bool
+	var verify bool
+	var smokeTest bool
+	var smokeArgs cli.StringSlice
 	var githubPath string
 	var name string
 	var arch string
 	var path string
+	var downloadURL string
+	var prefer cli.StringSlice
+	var avoid cli.StringSlice
 	var apply bool
+	var cacheDir string
+	var cacheMaxSize string
+	var httpTimeout time.Duration
+	var httpRetries int
+	var proxy string
+	var caBundle string
+	var workspaceDir string
+	var localRepo string
+	var target publish.Config
+	var output string
+	var lintMinPackages int
+	var lintMinSize string
+	var lintDisable cli.StringSlice
 
 	app := cli.NewApp()
 	app.Version = "0.0.1"
@@ -76,6 +89,31 @@ func main() {
 			Usage:       "Path",
 			Value:       "",
 			Destination: &path,
+		}, cli.StringFlag{
+			Name:        "url",
+			Usage:       "Download url template, eg. https://dl.k8s.io/release/{release}/bin/{os}/{arch}/kubectl{ext}",
+			Value:       "",
+			Destination: &downloadURL,
+		}, cli.StringSliceFlag{
+			Name:  "prefer",
+			Usage: "Preferred asset name fragment, when multiple assets exists for the same os/arch",
+			Value: &prefer,
+		}, cli.StringSliceFlag{
+			Name:  "avoid",
+			Usage: "Asset name fragment to avoid, when multiple assets exists for the same os/arch",
+			Value: &avoid,
+		}, cli.BoolFlag{
+			Name:        "verify",
+			Usage:       "Download all assets and verify them against the published checksums",
+			Destination: &verify,
+		}, cli.BoolFlag{
+			Name:        "smoke-test",
+			Usage:       "Run the linux/amd64 binary when linting and check that it prints the new version",
+			Destination: &smokeTest,
+		}, cli.StringSliceFlag{
+			Name:  "smoke-arg",
+			Usage: "Argument the binary is run with when smoke testing (default: --version)",
+			Value: &smokeArgs,
 		}, cli.BoolFlag{
 			Name:        "verbose",
 			Usage:       "Full debug log",
@@ -84,6 +122,83 @@ func main() {
 			Name:        "clean, c",
 			Usage:       "Clear all cached packages",
 			Destination: &clean,
+		}, cli.StringFlag{
+			Name:        "cache-dir",
+			Usage:       "Directory of the download cache",
+			Value:       "/tmp/gofish-bot",
+			EnvVar:      "GOFISH_BOT_CACHE_DIR",
+			Destination: &cacheDir,
+		}, cli.StringFlag{
+			Name:        "cache-max-size",
+			Usage:       "Maximum size of the download cache, eg. 200MB",
+			EnvVar:      "GOFISH_BOT_CACHE_MAX_SIZE",
+			Destination: &cacheMaxSize,
+		}, cli.DurationFlag{
+			Name:        "http-timeout",
+			Usage:       "Timeout of each http request",
+			Value:       5 * time.Minute,
+			EnvVar:      "GOFISH_BOT_HTTP_TIMEOUT",
+			Destination: &httpTimeout,
+		}, cli.IntFlag{
+			Name:        "http-retries",
+			Usage:       "Retries of http requests failing with transient errors",
+			Value:       3,
+			EnvVar:      "GOFISH_BOT_HTTP_RETRIES",
+			Destination: &httpRetries,
+		}, cli.StringFlag{
+			Name:        "proxy",
+			Usage:       "Proxy url for all http requests, defaults to HTTPS_PROXY",
+			EnvVar:      "GOFISH_BOT_PROXY",
+			Destination: &proxy,
+		}, cli.StringFlag{
+			Name:        "ca-bundle",
+			Usage:       "Path to PEM encoded certificates to trust in addition to the system certificates",
+			EnvVar:      "GOFISH_BOT_CA_BUNDLE",
+			Destination: &caBundle,
+		}, cli.StringFlag{
+			Name:        "workspace",
+			Usage:       "Directory the food is rendered and linted in (default: a temporary directory)",
+			EnvVar:      "GOFISH_BOT_WORKSPACE",
+			Destination: &workspaceDir,
+		}, cli.StringFlag{
+			Name:        "local-repo",
+			Usage:       "Commit to branches of this local fish-food clone instead of opening pull requests on GitHub",
+			EnvVar:      "GOFISH_BOT_LOCAL_REPO",
+			Destination: &localRepo,
+		}, cli.StringFlag{
+			Name:        "food-org",
+			Usage:       "Owner of the fish-food repository pull requests are opened in",
+			Value:       "fishworks",
+			EnvVar:      "GOFISH_BOT_FOOD_ORG",
+			Destination: &target.Org,
+		}, cli.StringFlag{
+			Name:        "food-repo",
+			Usage:       "Name of the fish-food repository",
+			Value:       "fish-food",
+			EnvVar:      "GOFISH_BOT_FOOD_REPO",
+			Destination: &target.Repo,
+		}, cli.StringFlag{
+			Name:        "base-branch",
+			Usage:       "Branch pull requests are opened against (default: the default branch of the repository)",
+			EnvVar:      "GOFISH_BOT_BASE_BRANCH",
+			Destination: &target.Base,
+		}, cli.StringFlag{
+			Name:        "output, o",
+			Usage:       "Format of the lint report, table or json",
+			Value:       "table",
+			Destination: &output,
+		}, cli.IntFlag{
+			Name:        "lint-min-packages",
+			Usage:       "Minimum number of packages of the food (default: 3)",
+			Destination: &lintMinPackages,
+		}, cli.StringFlag{
+			Name:        "lint-min-size",
+			Usage:       "Minimum size of a package, eg. 10KB (default: 100KB)",
+			Destination: &lintMinSize,
+		}, cli.StringSliceFlag{
+			Name:  "lint-disable",
+			Usage: "Lint rule id to disable, eg. min-size",
+			Value: &lintDisable,
 		},
 	}
 
@@ -94,8 +209,22 @@ func main() {
 			log.G(ctx).Logger.SetLevel(logrus.DebugLevel)
 		}
 
+		var maxSize uint64
+		var err error
+		if cacheMaxSize != "" {
+			maxSize, err = humanize.ParseBytes(cacheMaxSize)
+			if err != nil {
+				log.G(ctx).Fatalf("Invalid cache max size '%s': %v", cacheMaxSize, err)
+			}
+		}
+		downloadCache, err := cache.New(cacheDir, int64(maxSize))
+		if err != nil {
+			log.G(ctx).Fatalf("Error creating download cache: %v", err)
+		}
+		defer downloadCache.Close()
+
 		if clean {
-			clearDir(tmpDir)
+			downloadCache.Clear()
 			clearDir(home.Cache())
 		}
 
@@ -104,15 +233,22 @@ func main() {
 			panic(err)
 		}
 
-		githubOrg, err := envy.MustGet("GITHUB_ORG")
+		// The GitHub credentials are only required when publishing to GitHub
+		get := envy.MustGet
+		if localRepo != "" {
+			target.Type = publish.TypeGit
+			target.URL = localRepo
+			get = func(key string) (string, error) { return envy.Get(key, ""), nil }
+		}
+		githubOrg, err := get("GITHUB_ORG")
 		if err != nil {
 			log.G(ctx).Fatalf("Error getting Github token: %v", err)
 		}
-		githubName, err := envy.MustGet("GITHUB_NAME")
+		githubName, err := get("GITHUB_NAME")
 		if err != nil {
 			log.G(ctx).Fatalf("Error getting Github token: %v", err)
 		}
-		githubEmail, err := envy.MustGet("GITHUB_EMAIL")
+		githubEmail, err := get("GITHUB_EMAIL")
 		if err != nil {
 			log.G(ctx).Fatalf("Error getting Github token: %v", err)
 		}
@@ -126,31 +262,73 @@ func main() {
 
 		// Github
 		app := models.DesiredApp{
-			Name: name,
-			Repo: repo,
-			Org:  org,
-			Arch: arch,
-			Path: path,
+			Name:      name,
+			Repo:      repo,
+			Org:       org,
+			Arch:      arch,
+			Path:      path,
+			URL:       downloadURL,
+			SmokeArgs: smokeArgs,
 		}
 		log.G(ctx).Infof("%v", app)
 
-		client := gofishgithub.CreateClient(ctx)
+		httpClient, err := httpclient.New(httpclient.Options{
+			Timeout:  httpTimeout,
+			Retries:  httpRetries,
+			Proxy:    proxy,
+			CABundle: caBundle,
+		})
+		if err != nil {
+			log.G(ctx).Fatalf("Error creating http client: %v", err)
+		}
+		foodWorkspace, err := workspace.New(workspaceDir)
+		if err != nil {
+			log.G(ctx).Fatalf("Error creating workspace: %v", err)
+		}
+
+		client := gofishgithub.CreateClient
+		if localRepo != "" {
+			client = gofishgithub.CreateReadClient
+		}
 		goFish := &gofishgithub.GoFish{
-			Client:      client,
+			Client:      client(ctx, httpClient),
+			HTTPClient:  httpClient,
+			Cache:       downloadCache,
+			Workspace:   foodWorkspace,
 			BotOrg:      githubOrg,
-			FoodRepo:    "fish-food",
-			FoodOrg:     "fishworks",
+			FoodRepo:    target.Repo,
+			FoodOrg:     target.Org,
 			AuthorName:  githubName,
 			AuthorEmail: githubEmail,
 		}
 
+		target.Fork = githubOrg
+		goFish.Publisher, err = publish.New(target, goFish.Client, httpClient, publish.Author{Name: githubName, Email: githubEmail})
+		if err != nil {
+			log.G(ctx).Fatalf("Error creating publisher: %v", err)
+		}
+
+		lintConfig := lint.Config{
+			MinSize: lintMinSize,
+			Disable: lintDisable,
+		}
+		if c.IsSet("lint-min-packages") {
+			lintConfig.MinPackages = &lintMinPackages
+		}
 		g := github.Github{
 			GoFish: goFish,
+			Settings: models.Settings{
+				Prefer:    prefer,
+				Avoid:     avoid,
+				Verify:    verify,
+				SmokeTest: smokeTest,
+				Lint:      lintConfig,
+			},
 		}
 
 		application, err := g.CreateApplication(ctx, app)
 		if err != nil {
-			log.G(ctx).Errorf("Error handling: %s", app.Name)
+			log.G(ctx).Fatalf("Error handling %s: %v", app.Name, err)
 		}
 
 		headerFmt := color.New(color.FgGreen, color.Underline).SprintfFunc()
@@ -162,14 +340,35 @@ func main() {
 		tbl.AddRow(application.Organization, application.Name, application.Version)
 		tbl.Print()
 
-		g.CreateLuaFile(ctx, application)
+		err = g.CreateLuaFile(ctx, application)
+		if err != nil {
+			log.G(ctx).Fatalf("Could not write food: %v", err)
+		}
+		log.G(ctx).Infof("Food written to %s", foodWorkspace.FoodPath(application.Name))
 
-		err = g.GoFish.Lint(application)
+		application.LintReport, err = g.GoFish.Lint(application)
 		if err != nil {
-			if strings.Contains(err.Error(), "Installing failed") {
-				log.G(ctx).Fatal(err)
+			log.G(ctx).Fatal(err)
+		}
+		if output == "json" {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			enc.Encode(application.LintReport)
+		} else {
+			printer.LintReport(application.LintReport)
+		}
+
+		// Packages that can not be installed are never published, unless
+		// the rules lower the findings to warnings
+		report := application.LintReport
+		for _, f := range report.Errors() {
+			switch f.Rule {
+			case lint.RuleInstall, lint.RuleBinaryFormat, lint.RuleSmokeTest:
+				log.G(ctx).Fatalf("Installing failed: %s", report)
 			}
-			log.G(ctx).Warnf("Linting failed in cmd: %v", err)
+		}
+		if report.Failed() {
+			log.G(ctx).Warnf("Linting failed in cmd: %s", report)
 		} else {
 			log.G(ctx).Infof("Linting ok: %v", application.Name)
 		}
@@ -0,0 +1,120 @@
+package download
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/gofish-bot/gofish-bot/cache"
+	"github.com/gofish-bot/gofish-bot/log"
+)
+
+// StatusError is returned when the server responds with a non 2xx status
+type StatusError struct {
+	URL        string
+	StatusCode int
+	Status     string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("downloading %s failed: %s", e.URL, e.Status)
+}
+
+// IsNotFound reports whether err, or an error it wraps, is a 404 response
+func IsNotFound(err error) bool {
+	var statusErr *StatusError
+	return errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusNotFound
+}
+
+// Get returns the download of url from the cache, downloading it first if
+// it is not cached. Only complete downloads of successful responses are
+// added to the cache. The entry must be closed when it is no longer read.
+func Get(ctx context.Context, client *http.Client, downloadCache *cache.Cache, url string) (*cache.Entry, error) {
+	if entry, ok := downloadCache.Get(url); ok {
+		log.G(ctx).Debugf("Getting from cache: %s", url)
+		return entry, nil
+	}
+
+	resp, err := fetch(ctx, client, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	entry, err := downloadCache.Put(url, resp.Body, resp.ContentLength)
+	if err != nil {
+		return nil, fmt.Errorf("downloading %s failed: %v", url, err)
+	}
+	log.G(ctx).Debugf(" - %s: %d bytes, sha256 %s", url, entry.Size, entry.SHA256)
+	return entry, nil
+}
+
+// SHA256 downloads url, bypassing the cache, and returns the sha256 of the
+// content
+func SHA256(ctx context.Context, client *http.Client, url string) (string, error) {
+	resp, err := fetch(ctx, client, url, nil)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	h := sha256.New()
+	written, err := io.Copy(h, resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("downloading %s failed: %v", url, err)
+	}
+	if resp.ContentLength >= 0 && written != resp.ContentLength {
+		return "", fmt.Errorf("downloading %s failed: got %d of %d bytes", url, written, resp.ContentLength)
+	}
+	return fmt.Sprintf("%x", h.Sum(nil)), nil
+}
+
+// Prefix returns at most the first n bytes of url, bypassing the cache. The
+// range is requested from the server, so the rest of the content is not
+// transferred unless the server ignores the range.
+func Prefix(ctx context.Context, client *http.Client, url string, n int64) ([]byte, error) {
+	header := http.Header{}
+	header.Set("Range", fmt.Sprintf("bytes=0-%d", n-1))
+	resp, err := fetch(ctx, client, url, header)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	b, err := ioutil.ReadAll(io.LimitReader(resp.Body, n))
+	if err != nil {
+		return nil, fmt.Errorf("downloading %s failed: %v", url, err)
+	}
+	return b, nil
+}
+
+func fetch(ctx context.Context, client *http.Client, url string, header http.Header) (*http.Response, error) {
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	log.G(ctx).Debugf("Downloading: %s", url)
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	for k, v := range header {
+		req.Header[k] = v
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		resp.Body.Close()
+		return nil, &StatusError{URL: url, StatusCode: resp.StatusCode, Status: resp.Status}
+	}
+	return resp, nil
+}
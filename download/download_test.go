@@ -0,0 +1,138 @@
+package download
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/gofish-bot/gofish-bot/cache"
+)
+
+func TestGet(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ok.tar.gz", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("content"))
+	})
+	mux.HandleFunc("/missing.tar.gz", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "<html>Not Found</html>", http.StatusNotFound)
+	})
+	mux.HandleFunc("/limited.tar.gz", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(`{"message": "API rate limit exceeded"}`))
+	})
+	mux.HandleFunc("/partial.tar.gz", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", "100")
+		w.Write([]byte("content"))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	tests := []struct {
+		name    string
+		path    string
+		wantSha string
+		wantErr bool
+	}{
+		{name: "ok", path: "/ok.tar.gz", wantSha: "ed7002b439e9ac845f22357d822bac1444730fbdb6016d3ec9432297b9ec9f73"},
+		{name: "not found", path: "/missing.tar.gz", wantErr: true},
+		{name: "rate limited", path: "/limited.tar.gz", wantErr: true},
+		{name: "partial", path: "/partial.tar.gz", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "gofish-bot-download")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(dir)
+			c, err := cache.New(dir, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			url := server.URL + tt.path
+			entry, err := Get(context.Background(), server.Client(), c, url)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Get() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				if _, ok := c.Get(url); ok {
+					t.Errorf("Get() cached a failed download")
+				}
+				return
+			}
+			if entry.SHA256 != tt.wantSha {
+				t.Errorf("Get() sha256 = %s, want %s", entry.SHA256, tt.wantSha)
+			}
+			if _, ok := c.Get(url); !ok {
+				t.Errorf("Get() did not cache the download")
+			}
+		})
+	}
+}
+
+func TestSHA256(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ok.tar.gz", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("content"))
+	})
+	mux.HandleFunc("/gone.tar.gz", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "Gone", http.StatusGone)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	sha, err := SHA256(context.Background(), server.Client(), server.URL+"/ok.tar.gz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sha != "ed7002b439e9ac845f22357d822bac1444730fbdb6016d3ec9432297b9ec9f73" {
+		t.Errorf("SHA256() = %s", sha)
+	}
+
+	_, err = SHA256(context.Background(), server.Client(), server.URL+"/gone.tar.gz")
+	statusErr, ok := err.(*StatusError)
+	if !ok || statusErr.StatusCode != http.StatusGone {
+		t.Errorf("SHA256() error = %v, want status 410", err)
+	}
+}
+
+func TestPrefix(t *testing.T) {
+	content := []byte("0123456789")
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ranged", func(w http.ResponseWriter, r *http.Request) {
+		http.ServeContent(w, r, "ranged", time.Time{}, bytes.NewReader(content))
+	})
+	mux.HandleFunc("/full", func(w http.ResponseWriter, r *http.Request) {
+		w.Write(content)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	tests := []struct {
+		name string
+		path string
+		n    int64
+		want string
+	}{
+		{name: "ranged", path: "/ranged", n: 4, want: "0123"},
+		{name: "range ignored", path: "/full", n: 4, want: "0123"},
+		{name: "shorter than n", path: "/ranged", n: 20, want: "0123456789"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Prefix(context.Background(), server.Client(), server.URL+tt.path, tt.n)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("Prefix() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
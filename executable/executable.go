@@ -0,0 +1,151 @@
+package executable
+
+import (
+	"bytes"
+	"debug/elf"
+	"debug/macho"
+	"debug/pe"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Check parses the executable at path and verifies that it is built for the
+// os and arch of the package. Scripts starting with #! are accepted for all
+// operating systems but windows.
+func Check(path, goos, goarch string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	magic := make([]byte, 4)
+	if _, err := io.ReadFull(f, magic); err != nil {
+		return fmt.Errorf("%s is not an executable: %v", path, err)
+	}
+
+	format, osName, archs, err := identify(f, magic)
+	if err != nil {
+		return fmt.Errorf("%s is not a valid executable: %v", path, err)
+	}
+
+	if osName != goos && !(osName == "unix" && goos != "windows") {
+		return fmt.Errorf("%s is a %s executable for %s, not for %s", path, format, osName, goos)
+	}
+	if archs == nil {
+		return nil
+	}
+	for _, arch := range archs {
+		if arch == goarch {
+			return nil
+		}
+	}
+	return fmt.Errorf("%s is a %s executable for %s, not for %s", path, format, strings.Join(archs, ", "), goarch)
+}
+
+// identify returns the format, operating system and cpu architectures of the
+// executable. Scripts are reported as unix without architectures.
+func identify(f *os.File, magic []byte) (string, string, []string, error) {
+	switch {
+	case bytes.HasPrefix(magic, []byte("#!")):
+		return "script", "unix", nil, nil
+
+	case bytes.Equal(magic, []byte(elf.ELFMAG)):
+		e, err := elf.NewFile(f)
+		if err != nil {
+			return "", "", nil, err
+		}
+		return "ELF", elfOS(e.OSABI), []string{elfArch(e)}, nil
+
+	case bytes.HasPrefix(magic, []byte("MZ")):
+		p, err := pe.NewFile(f)
+		if err != nil {
+			return "", "", nil, err
+		}
+		return "PE", "windows", []string{peArch(p.Machine)}, nil
+	}
+
+	if fat, err := macho.NewFatFile(f); err == nil {
+		archs := []string{}
+		for _, arch := range fat.Arches {
+			archs = append(archs, machoArch(arch.Cpu))
+		}
+		return "Mach-O universal", "darwin", archs, nil
+	}
+	m, err := macho.NewFile(f)
+	if err != nil {
+		return "", "", nil, fmt.Errorf("unknown format")
+	}
+	return "Mach-O", "darwin", []string{machoArch(m.Cpu)}, nil
+}
+
+func elfOS(abi elf.OSABI) string {
+	switch abi {
+	case elf.ELFOSABI_NONE, elf.ELFOSABI_LINUX:
+		return "linux"
+	case elf.ELFOSABI_FREEBSD:
+		return "freebsd"
+	case elf.ELFOSABI_NETBSD:
+		return "netbsd"
+	case elf.ELFOSABI_OPENBSD:
+		return "openbsd"
+	case elf.ELFOSABI_SOLARIS:
+		return "solaris"
+	}
+	return abi.String()
+}
+
+func elfArch(e *elf.File) string {
+	switch e.Machine {
+	case elf.EM_X86_64:
+		return "amd64"
+	case elf.EM_386:
+		return "386"
+	case elf.EM_AARCH64:
+		return "arm64"
+	case elf.EM_ARM:
+		return "arm"
+	case elf.EM_PPC64:
+		if e.Data == elf.ELFDATA2LSB {
+			return "ppc64le"
+		}
+		return "ppc64"
+	case elf.EM_S390:
+		return "s390x"
+	case elf.EM_RISCV:
+		return "riscv64"
+	}
+	return e.Machine.String()
+}
+
+func machoArch(cpu macho.Cpu) string {
+	switch cpu {
+	case macho.CpuAmd64:
+		return "amd64"
+	case macho.Cpu386:
+		return "386"
+	case macho.CpuArm64:
+		return "arm64"
+	case macho.CpuArm:
+		return "arm"
+	case macho.CpuPpc64:
+		return "ppc64"
+	}
+	return cpu.String()
+}
+
+func peArch(machine uint16) string {
+	switch machine {
+	case pe.IMAGE_FILE_MACHINE_AMD64:
+		return "amd64"
+	case pe.IMAGE_FILE_MACHINE_I386:
+		return "386"
+	case pe.IMAGE_FILE_MACHINE_ARM64:
+		return "arm64"
+	case pe.IMAGE_FILE_MACHINE_ARMNT:
+		return "arm"
+	}
+	return fmt.Sprintf("0x%x", machine)
+}
@@ -0,0 +1,130 @@
+package executable
+
+import (
+	"bytes"
+	"debug/elf"
+	"debug/macho"
+	"debug/pe"
+	"encoding/binary"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func elfHeader(machine elf.Machine, abi elf.OSABI) []byte {
+	b := &bytes.Buffer{}
+	b.Write([]byte{0x7f, 'E', 'L', 'F', byte(elf.ELFCLASS64), byte(elf.ELFDATA2LSB), byte(elf.EV_CURRENT), byte(abi)})
+	b.Write(make([]byte, 8))
+	binary.Write(b, binary.LittleEndian, uint16(elf.ET_EXEC))
+	binary.Write(b, binary.LittleEndian, uint16(machine))
+	binary.Write(b, binary.LittleEndian, uint32(elf.EV_CURRENT))
+	b.Write(make([]byte, 8+8+8+4))
+	binary.Write(b, binary.LittleEndian, uint16(64))
+	b.Write(make([]byte, 10))
+	return b.Bytes()
+}
+
+func machoHeader(cpu macho.Cpu) []byte {
+	b := &bytes.Buffer{}
+	binary.Write(b, binary.LittleEndian, macho.FileHeader{
+		Magic: macho.Magic64,
+		Cpu:   cpu,
+		Type:  macho.TypeExec,
+	})
+	b.Write(make([]byte, 4))
+	return b.Bytes()
+}
+
+func fatHeader(cpus ...macho.Cpu) []byte {
+	b := &bytes.Buffer{}
+	binary.Write(b, binary.BigEndian, []uint32{macho.MagicFat, uint32(len(cpus))})
+	offset := uint32(4096)
+	thin := [][]byte{}
+	for _, cpu := range cpus {
+		h := machoHeader(cpu)
+		binary.Write(b, binary.BigEndian, macho.FatArchHeader{Cpu: cpu, Offset: offset, Size: uint32(len(h))})
+		thin = append(thin, h)
+		offset += 4096
+	}
+	for _, h := range thin {
+		b.Write(make([]byte, 4096-b.Len()%4096))
+		b.Write(h)
+	}
+	return b.Bytes()
+}
+
+func peHeader(machine uint16) []byte {
+	b := &bytes.Buffer{}
+	dos := make([]byte, 0x40)
+	dos[0], dos[1] = 'M', 'Z'
+	binary.LittleEndian.PutUint32(dos[0x3c:], 0x40)
+	b.Write(dos)
+	b.Write([]byte{'P', 'E', 0, 0})
+	binary.Write(b, binary.LittleEndian, pe.FileHeader{Machine: machine})
+	b.Write(make([]byte, 512))
+	return b.Bytes()
+}
+
+func TestCheck(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gofish-bot-executable")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	self, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	selfContent, err := ioutil.ReadFile(self)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherOS := "windows"
+	if runtime.GOOS == "windows" {
+		otherOS = "linux"
+	}
+
+	tests := []struct {
+		name    string
+		content []byte
+		os      string
+		arch    string
+		wantErr bool
+	}{
+		{name: "test binary", content: selfContent, os: runtime.GOOS, arch: runtime.GOARCH},
+		{name: "test binary on other os", content: selfContent, os: otherOS, arch: runtime.GOARCH, wantErr: true},
+		{name: "linux amd64", content: elfHeader(elf.EM_X86_64, elf.ELFOSABI_NONE), os: "linux", arch: "amd64"},
+		{name: "linux arm64", content: elfHeader(elf.EM_AARCH64, elf.ELFOSABI_LINUX), os: "linux", arch: "arm64"},
+		{name: "linux wrong arch", content: elfHeader(elf.EM_AARCH64, elf.ELFOSABI_NONE), os: "linux", arch: "amd64", wantErr: true},
+		{name: "linux binary for darwin", content: elfHeader(elf.EM_X86_64, elf.ELFOSABI_NONE), os: "darwin", arch: "amd64", wantErr: true},
+		{name: "freebsd binary for linux", content: elfHeader(elf.EM_X86_64, elf.ELFOSABI_FREEBSD), os: "linux", arch: "amd64", wantErr: true},
+		{name: "darwin amd64", content: machoHeader(macho.CpuAmd64), os: "darwin", arch: "amd64"},
+		{name: "darwin wrong arch", content: machoHeader(macho.CpuArm64), os: "darwin", arch: "amd64", wantErr: true},
+		{name: "darwin binary for linux", content: machoHeader(macho.CpuAmd64), os: "linux", arch: "amd64", wantErr: true},
+		{name: "darwin universal amd64", content: fatHeader(macho.CpuAmd64, macho.CpuArm64), os: "darwin", arch: "amd64"},
+		{name: "darwin universal arm64", content: fatHeader(macho.CpuAmd64, macho.CpuArm64), os: "darwin", arch: "arm64"},
+		{name: "darwin universal wrong arch", content: fatHeader(macho.CpuAmd64, macho.CpuArm64), os: "darwin", arch: "386", wantErr: true},
+		{name: "windows amd64", content: peHeader(pe.IMAGE_FILE_MACHINE_AMD64), os: "windows", arch: "amd64"},
+		{name: "windows wrong arch", content: peHeader(pe.IMAGE_FILE_MACHINE_I386), os: "windows", arch: "amd64", wantErr: true},
+		{name: "windows binary for linux", content: peHeader(pe.IMAGE_FILE_MACHINE_AMD64), os: "linux", arch: "amd64", wantErr: true},
+		{name: "script", content: []byte("#!/bin/sh\necho hello\n"), os: "linux", arch: "amd64"},
+		{name: "script for windows", content: []byte("#!/bin/sh\necho hello\n"), os: "windows", arch: "amd64", wantErr: true},
+		{name: "html", content: []byte("<html>Not Found</html>"), os: "linux", arch: "amd64", wantErr: true},
+		{name: "empty", content: []byte{}, os: "linux", arch: "amd64", wantErr: true},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, string(rune('a'+i)))
+			if err := ioutil.WriteFile(path, tt.content, 0755); err != nil {
+				t.Fatal(err)
+			}
+			err := Check(path, tt.os, tt.arch)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Check() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
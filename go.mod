@@ -16,6 +16,8 @@ require (
 	github.com/sirupsen/logrus v1.8.1
 	github.com/urfave/cli v1.22.5
 	github.com/yuin/gluamapper v0.0.0-20150323120927-d836955830e7
+	github.com/yuin/goldmark v1.4.0
 	github.com/yuin/gopher-lua v0.0.0-20210529063254-f4c35e4016d9
+	golang.org/x/crypto v0.0.0-20200622213623-75b288015ac9
 	golang.org/x/oauth2 v0.0.0-20210615190721-d04028783cf1
 )
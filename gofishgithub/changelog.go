@@ -0,0 +1,146 @@
+package gofishgithub
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/blang/semver"
+
+	"github.com/gofish-bot/gofish-bot/models"
+)
+
+// maxBodySize is the maximum number of characters in a pull request body
+const maxBodySize = 65536
+
+// changelog aggregates the release notes of every release after the current
+// version up to the new version, newest first and collapsed per release. The
+// notes are escaped with cleanMarkdown and the oldest releases are left out
+// when the changelog does not fit into size characters.
+func changelog(application *models.Application, size int) string {
+	notes := newReleaseNotes(application)
+
+	// omitted notes the releases from i on that did not fit
+	omitted := func(i int) string {
+		if i >= len(notes) {
+			return ""
+		}
+		if notes[i].Link == "" {
+			return fmt.Sprintf("\n%d older releases omitted\n", len(notes)-i)
+		}
+		return fmt.Sprintf("\n%d older releases omitted, see %s\n", len(notes)-i, notes[i].Link)
+	}
+
+	var b strings.Builder
+	for i, note := range notes {
+		block := releaseBlock(note)
+		if i == 0 {
+			// The newest release is shortened rather than left out
+			block = fitReleaseBlock(note, size-len(omitted(1)))
+		}
+		if b.Len()+len(block)+len(omitted(i+1)) > size {
+			if b.Len()+len(omitted(i)) <= size {
+				b.WriteString(omitted(i))
+			}
+			break
+		}
+		b.WriteString(block)
+	}
+	return b.String()
+}
+
+// newReleaseNotes returns the notes of the releases after the current version
+// up to the new version, newest first. Pre-releases are skipped unless they are
+// the new version. Without a parsable current version only the notes of the
+// new release are returned.
+func newReleaseNotes(application *models.Application) []models.ReleaseNote {
+	newest := models.ReleaseNote{
+		Name:        application.ReleaseName,
+		Version:     application.Version,
+		Link:        application.ReleaseLink,
+		Description: application.ReleaseDescription,
+	}
+
+	current, err := semver.ParseTolerant(application.CurrentVersion)
+	if err != nil {
+		if newest.Description == "" {
+			return nil
+		}
+		return []models.ReleaseNote{newest}
+	}
+	target, err := semver.ParseTolerant(application.Version)
+	if err != nil {
+		if newest.Description == "" {
+			return nil
+		}
+		return []models.ReleaseNote{newest}
+	}
+
+	type versioned struct {
+		version semver.Version
+		note    models.ReleaseNote
+	}
+	releases := []versioned{}
+	seen := map[string]bool{}
+	for _, note := range application.ReleaseNotes {
+		v, err := semver.ParseTolerant(note.Version)
+		if err != nil || seen[v.String()] || v.LTE(current) || v.GT(target) {
+			continue
+		}
+		if len(v.Pre) > 0 && !v.Equals(target) {
+			continue
+		}
+		if strings.TrimSpace(note.Description) == "" {
+			continue
+		}
+		seen[v.String()] = true
+		releases = append(releases, versioned{v, note})
+	}
+	if len(releases) == 0 && newest.Description != "" {
+		return []models.ReleaseNote{newest}
+	}
+
+	sort.Slice(releases, func(i, j int) bool {
+		return releases[i].version.GT(releases[j].version)
+	})
+	notes := []models.ReleaseNote{}
+	for _, r := range releases {
+		notes = append(notes, r.note)
+	}
+	return notes
+}
+
+// releaseBlock renders the escaped note of a release in a collapsed block
+func releaseBlock(note models.ReleaseNote) string {
+	summary := note.Name
+	if note.Link != "" {
+		summary = fmt.Sprintf("<a href=\"%s\">%s</a>", note.Link, note.Name)
+	}
+	return fmt.Sprintf("<details><summary>%s</summary>\n\n%s\n\n</details>\n", summary, cleanMarkdown(note.Description))
+}
+
+// fitReleaseBlock renders the release block, shortening the description until
+// the block fits into size characters. The description is shortened before it
+// is escaped, so no escape sequence is cut.
+func fitReleaseBlock(note models.ReleaseNote, size int) string {
+	const truncated = "\n\n*Release notes truncated*"
+
+	block := releaseBlock(note)
+	for len(block) > size && note.Description != "" {
+		overflow := len(block) - size + len(truncated)
+		cut := len(note.Description) - overflow
+		if cut < 0 {
+			cut = 0
+		}
+		for cut > 0 && !utf8.RuneStart(note.Description[cut]) {
+			cut--
+		}
+		note.Description = strings.TrimSuffix(note.Description[:cut], truncated) + truncated
+		if cut == 0 {
+			note.Description = ""
+		}
+		block = releaseBlock(note)
+	}
+	return block
+}
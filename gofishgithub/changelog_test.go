@@ -0,0 +1,109 @@
+package gofishgithub
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gofish-bot/gofish-bot/models"
+)
+
+func changelogApp(current string) *models.Application {
+	return &models.Application{
+		ReleaseName:        "v1.5.3",
+		ReleaseLink:        "https://example.com/v1.5.3",
+		ReleaseDescription: "notes 1.5.3",
+		Version:            "1.5.3",
+		CurrentVersion:     current,
+		ReleaseNotes: []models.ReleaseNote{
+			{Name: "v1.6.0-rc.1", Version: "1.6.0-rc.1", Description: "notes 1.6.0-rc.1"},
+			{Name: "v1.5.3", Version: "1.5.3", Link: "https://example.com/v1.5.3", Description: "notes 1.5.3"},
+			{Name: "v1.4.0", Version: "1.4.0", Link: "https://example.com/v1.4.0", Description: "notes 1.4.0"},
+			{Name: "v1.5.0-beta.1", Version: "1.5.0-beta.1", Description: "notes 1.5.0-beta.1"},
+			{Name: "v1.5.0", Version: "1.5.0", Link: "https://example.com/v1.5.0", Description: "notes 1.5.0, fixes #12"},
+			{Name: "v1.3.0", Version: "1.3.0", Description: ""},
+			{Name: "v1.2.0", Version: "1.2.0", Description: "notes 1.2.0"},
+			{Name: "nightly", Version: "nightly", Description: "notes nightly"},
+		},
+	}
+}
+
+func Test_newReleaseNotes(t *testing.T) {
+	tests := []struct {
+		name    string
+		current string
+		want    []string
+	}{
+		{"every release since the current version", "1.2.0", []string{"v1.5.3", "v1.5.0", "v1.4.0"}},
+		{"current version with v", "v1.4.0", []string{"v1.5.3", "v1.5.0"}},
+		{"only the new release", "1.5.0", []string{"v1.5.3"}},
+		{"unparsable current version", "latest", []string{"v1.5.3"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := []string{}
+			for _, note := range newReleaseNotes(changelogApp(tt.current)) {
+				got = append(got, note.Name)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("newReleaseNotes() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_changelog(t *testing.T) {
+	app := changelogApp("1.2.0")
+
+	got := changelog(app, maxBodySize)
+	want := `<details><summary><a href="https://example.com/v1.5.3">v1.5.3</a></summary>
+
+notes 1.5.3
+
+</details>
+<details><summary><a href="https://example.com/v1.5.0">v1.5.0</a></summary>
+
+notes 1.5.0, fixes #<!-- -->12
+
+</details>
+<details><summary><a href="https://example.com/v1.4.0">v1.4.0</a></summary>
+
+notes 1.4.0
+
+</details>
+`
+	if got != want {
+		t.Errorf("changelog() = %v, want %v", got, want)
+	}
+
+	// The oldest releases are left out first
+	got = changelog(app, 250)
+	if !strings.Contains(got, "v1.5.3</a>") || strings.Contains(got, "v1.4.0</a>") {
+		t.Errorf("changelog() kept the wrong releases: %v", got)
+	}
+	if !strings.HasSuffix(got, "</details>\n\n2 older releases omitted, see https://example.com/v1.5.0\n") {
+		t.Errorf("changelog() does not tell about the omitted releases: %v", got)
+	}
+	if len(got) > 250 {
+		t.Errorf("changelog() is %d characters, want at most 250", len(got))
+	}
+}
+
+func Test_changelogTruncated(t *testing.T) {
+	app := changelogApp("1.5.0")
+	app.ReleaseNotes[1].Description = strings.Repeat("# Fixed @someone's bug 🐟\n", 5000)
+
+	got := changelog(app, maxBodySize)
+	if len(got) > maxBodySize {
+		t.Errorf("changelog() is %d characters, want at most %d", len(got), maxBodySize)
+	}
+	if !strings.Contains(got, "*Release notes truncated*\n\n</details>") {
+		t.Errorf("changelog() does not end with the truncated release: %s", got[len(got)-200:])
+	}
+	if !strings.HasPrefix(got, "<details>") || !strings.Contains(got, "@<!-- -->someone") {
+		t.Errorf("changelog() is not escaped: %s", got[:200])
+	}
+	if strings.Count(got, "<!--") != strings.Count(got, "-->") {
+		t.Errorf("changelog() cut an escape sequence")
+	}
+}
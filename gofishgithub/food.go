@@ -0,0 +1,152 @@
+package gofishgithub
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/fishworks/gofish"
+	"github.com/yuin/gluamapper"
+	lua "github.com/yuin/gopher-lua"
+)
+
+// Limits of evaluating a food. Foods are fetched from GitHub and must not be
+// able to access the machine of the bot or keep it busy. The memory of a
+// food is not measured, it is bounded by the stack limits of the Lua state,
+// the size of the strings string.rep and table.concat create, and the
+// timeout.
+var foodTimeout = 5 * time.Second
+
+const (
+	foodMaxSize          = 256 << 10
+	foodMaxString        = 1 << 20
+	foodCallStackSize    = 200
+	foodRegistrySize     = 1024
+	foodRegistryGrowStep = 256
+	foodRegistryMaxSize  = 8 << 10
+)
+
+// foodLibs are the libraries opened for foods. The unsafe functions of the
+// base library are removed after opening it.
+var foodLibs = []struct {
+	name string
+	open lua.LGFunction
+}{
+	{lua.BaseLibName, lua.OpenBase},
+	{lua.TabLibName, lua.OpenTable},
+	{lua.StringLibName, lua.OpenString},
+	{lua.MathLibName, lua.OpenMath},
+}
+
+// unsafeGlobals can load code, access files or the environment
+var unsafeGlobals = []string{
+	"collectgarbage", "dofile", "getfenv", "load", "loadfile", "loadstring",
+	"module", "newproxy", "print", "require", "setfenv", "_printregs",
+}
+
+// evalFood evaluates the lua content of a food in a sandbox, with only the
+// string, table and math libraries and the safe base functions available
+func evalFood(content string) (*gofish.Food, error) {
+	if len(content) > foodMaxSize {
+		return nil, fmt.Errorf("food is too large: %d bytes, the limit is %d", len(content), foodMaxSize)
+	}
+
+	l := lua.NewState(lua.Options{
+		SkipOpenLibs:        true,
+		CallStackSize:       foodCallStackSize,
+		RegistrySize:        foodRegistrySize,
+		RegistryGrowStep:    foodRegistryGrowStep,
+		RegistryMaxSize:     foodRegistryMaxSize,
+		MinimizeStackMemory: true,
+	})
+	defer l.Close()
+
+	for _, lib := range foodLibs {
+		err := l.CallByParam(lua.P{Fn: l.NewFunction(lib.open), NRet: 0, Protect: true}, lua.LString(lib.name))
+		if err != nil {
+			return nil, err
+		}
+	}
+	for _, name := range unsafeGlobals {
+		l.SetGlobal(name, lua.LNil)
+	}
+	limitStrings(l)
+
+	ctx, cancel := context.WithTimeout(context.Background(), foodTimeout)
+	defer cancel()
+	l.SetContext(ctx)
+
+	fn, err := l.LoadString(content)
+	if err != nil {
+		return nil, err
+	}
+	l.Push(fn)
+	if err := l.PCall(0, lua.MultRet, nil); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return nil, fmt.Errorf("evaluating food took longer than %s", foodTimeout)
+		}
+		return nil, err
+	}
+
+	table, ok := l.GetGlobal("food").(*lua.LTable)
+	if !ok {
+		return nil, fmt.Errorf("food is not defined as a table")
+	}
+	var food gofish.Food
+	if err := gluamapper.Map(table, &food); err != nil {
+		return nil, err
+	}
+	return &food, nil
+}
+
+// limitStrings replaces string.rep and table.concat with functions refusing
+// to create strings larger than foodMaxString
+func limitStrings(l *lua.LState) {
+	if str, ok := l.GetGlobal(lua.StringLibName).(*lua.LTable); ok {
+		str.RawSetString("rep", l.NewFunction(stringRep))
+	}
+	if tbl, ok := l.GetGlobal(lua.TabLibName).(*lua.LTable); ok {
+		concat := tbl.RawGetString("concat")
+		tbl.RawSetString("concat", l.NewFunction(func(l *lua.LState) int {
+			checkTableConcat(l)
+			l.Insert(concat, 1)
+			l.Call(l.GetTop()-1, lua.MultRet)
+			return l.GetTop()
+		}))
+	}
+}
+
+// stringRep is string.rep with a limited result
+func stringRep(l *lua.LState) int {
+	str := l.CheckString(1)
+	n := l.CheckInt(2)
+	if n <= 0 {
+		l.Push(lua.LString(""))
+		return 1
+	}
+	if len(str) > 0 && n > foodMaxString/len(str) {
+		l.RaiseError("string.rep result is larger than %d bytes", foodMaxString)
+		return 0
+	}
+	l.Push(lua.LString(strings.Repeat(str, n)))
+	return 1
+}
+
+// checkTableConcat raises an error when the result of table.concat would be
+// larger than foodMaxString
+func checkTableConcat(l *lua.LState) {
+	tbl := l.CheckTable(1)
+	sep := len(l.OptString(2, ""))
+	size := 0
+	for i := l.OptInt(3, 1); i <= l.OptInt(4, tbl.Len()) && i <= tbl.Len(); i++ {
+		value := tbl.RawGetInt(i)
+		if !lua.LVCanConvToString(value) {
+			return
+		}
+		size += len(lua.LVAsString(value)) + sep
+		if size > foodMaxString {
+			l.RaiseError("table.concat result is larger than %d bytes", foodMaxString)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package gofishgithub
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_evalFood(t *testing.T) {
+	defer func(timeout time.Duration) { foodTimeout = timeout }(foodTimeout)
+	foodTimeout = 500 * time.Millisecond
+
+	valid := `local name = "tool"
+local version = "1.2.3"
+
+food = {
+    name = name,
+    description = "A tool",
+    homepage = "https://example.com",
+    version = version,
+    packages = {
+        {
+            os = "linux",
+            arch = "amd64",
+            url = "https://example.com/" .. name .. "/releases/download/v" .. version .. "/" .. string.upper(name) .. ".tar.gz",
+            sha256 = string.rep("0", 64),
+            resources = {
+                {
+                    path = name,
+                    installpath = "bin/" .. name,
+                    executable = true
+                }
+            }
+        }
+    }
+}
+`
+
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{name: "valid", content: valid},
+		{name: "os.execute", content: `os.execute("touch /tmp/gofish-bot-pwned")` + "\n" + valid, wantErr: "attempt to"},
+		{name: "io.open", content: `io.open("/etc/passwd")` + "\n" + valid, wantErr: "attempt to"},
+		{name: "require", content: `require("os")` + "\n" + valid, wantErr: "attempt to"},
+		{name: "dofile", content: `dofile("/etc/passwd")` + "\n" + valid, wantErr: "attempt to"},
+		{name: "loadstring", content: `loadstring("return 1")()` + "\n" + valid, wantErr: "attempt to"},
+		{name: "endless loop", content: `while true do end`, wantErr: "took longer than"},
+		{name: "endless recursion", content: `local function f() return 1 + f() end f()`, wantErr: "stack overflow"},
+		{name: "huge string", content: `local s = string.rep("x", 1024 * 1024 * 1024)`, wantErr: "larger than"},
+		{name: "too large", content: valid + "--" + strings.Repeat("x", foodMaxSize), wantErr: "too large"},
+		{name: "no food", content: `local name = "tool"`, wantErr: "not defined"},
+		{name: "food is not a table", content: `food = "tool"`, wantErr: "not defined"},
+		{name: "syntax error", content: `food = {`, wantErr: "EOF"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start := time.Now()
+			food, err := evalFood(tt.content)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("evalFood() error = %v", err)
+				}
+				if food.Version != "1.2.3" || food.Packages[0].URL != "https://example.com/tool/releases/download/v1.2.3/TOOL.tar.gz" {
+					t.Errorf("evalFood() = %+v", food)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("evalFood() error = %v, want %s", err, tt.wantErr)
+			}
+			if time.Since(start) > 2*time.Second {
+				t.Errorf("evalFood() took %s", time.Since(start))
+			}
+		})
+	}
+}
+
+func Test_evalFood_strings(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{name: "string.rep", content: `local s = string.rep("x", 1024 * 1024 + 1)`, wantErr: "larger than"},
+		{name: "string.rep overflow", content: `local s = string.rep("xx", 2 ^ 62)`, wantErr: "larger than"},
+		{name: "string.rep limit", content: `food = { name = string.rep("x", 1024 * 1024) }`},
+		{name: "table.concat", content: `local t = {} local s = string.rep("x", 1000000) for i = 1, 100 do t[i] = s end local all = table.concat(t)`, wantErr: "larger than"},
+		{name: "table.concat range", content: `local t = {} local s = string.rep("x", 1000000) for i = 1, 100 do t[i] = s end food = { name = table.concat(t, ",", 2, 2) }`},
+		{name: "table.concat invalid", content: `local s = table.concat({ {} })`, wantErr: "invalid value"},
+		{name: "registry overflow", content: `local t = {} for i = 1, 10000 do t[i] = i end local all = {unpack(t)}`, wantErr: "registry overflow"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := evalFood(tt.content)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("evalFood() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("evalFood() error = %v, want %s", err, tt.wantErr)
+			}
+		})
+	}
+}
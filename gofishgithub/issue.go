@@ -0,0 +1,49 @@
+package gofishgithub
+
+import (
+	"context"
+
+	ghApi "github.com/google/go-github/v32/github"
+
+	"github.com/gofish-bot/gofish-bot/log"
+	"github.com/gofish-bot/gofish-bot/publish"
+)
+
+// CreateIssue opens an issue in the food repository on GitHub, unless the
+// bot already opened an issue with the same title that is still open. No
+// issue is opened when the foods are not published to GitHub.
+func (p *GoFish) CreateIssue(ctx context.Context, title, body string) error {
+	gh, ok := p.publisher().(*publish.GitHub)
+	if !ok {
+		log.G(ctx).Infof("Not publishing to GitHub, skipping issue: %s", title)
+		return nil
+	}
+
+	user, _, err := gh.Client.Users.Get(ctx, "")
+	if err != nil {
+		return err
+	}
+	existing, _, err := gh.Client.Issues.ListByRepo(ctx, gh.Upstream, gh.Repo, &ghApi.IssueListByRepoOptions{
+		State:   "open",
+		Creator: user.GetLogin(),
+	})
+	if err != nil {
+		return err
+	}
+	for _, issue := range existing {
+		if issue.GetTitle() == title && !issue.IsPullRequest() {
+			log.G(ctx).Infof("Issue already exists: %s", issue.GetHTMLURL())
+			return nil
+		}
+	}
+
+	issue, _, err := gh.Client.Issues.Create(ctx, gh.Upstream, gh.Repo, &ghApi.IssueRequest{
+		Title: &title,
+		Body:  &body,
+	})
+	if err != nil {
+		return err
+	}
+	log.G(ctx).Infof("Issue created: %s", issue.GetHTMLURL())
+	return nil
+}
@@ -0,0 +1,71 @@
+package gofishgithub
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	ghApi "github.com/google/go-github/v32/github"
+
+	"github.com/gofish-bot/gofish-bot/publish"
+)
+
+func TestGoFish_CreateIssue(t *testing.T) {
+	tests := []struct {
+		name        string
+		title       string
+		wantCreated bool
+	}{
+		{"new issue", "Checksum mismatches or dead urls: tool", true},
+		{"existing issue", "Checksum mismatches or dead urls: other", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			created := false
+			mux := http.NewServeMux()
+			mux.HandleFunc("/user", func(w http.ResponseWriter, r *http.Request) {
+				json.NewEncoder(w).Encode(&ghApi.User{Login: ghApi.String("gofish-bot")})
+			})
+			mux.HandleFunc("/repos/fishworks/fish-food/issues", func(w http.ResponseWriter, r *http.Request) {
+				if r.Method == http.MethodPost {
+					created = true
+					json.NewEncoder(w).Encode(&ghApi.Issue{HTMLURL: ghApi.String("issue")})
+					return
+				}
+				if creator := r.URL.Query().Get("creator"); creator != "gofish-bot" {
+					t.Errorf("issues listed by creator %s, want the bot login", creator)
+				}
+				json.NewEncoder(w).Encode([]*ghApi.Issue{{Title: ghApi.String("Checksum mismatches or dead urls: other")}})
+			})
+			server := httptest.NewServer(mux)
+			defer server.Close()
+
+			client := ghApi.NewClient(server.Client())
+			client.BaseURL, _ = url.Parse(server.URL + "/")
+			p := &GoFish{
+				Client:   client,
+				BotOrg:   "gofish-bot-org",
+				FoodOrg:  "fishworks",
+				FoodRepo: "fish-food",
+			}
+
+			if err := p.CreateIssue(context.Background(), tt.title, "body"); err != nil {
+				t.Fatal(err)
+			}
+			if created != tt.wantCreated {
+				t.Errorf("CreateIssue() created = %v, want %v", created, tt.wantCreated)
+			}
+		})
+	}
+}
+
+func TestGoFish_CreateIssue_notGitHub(t *testing.T) {
+	// Without a GitHub publisher the GitHub client is never used
+	p := &GoFish{Publisher: &publish.Git{Dir: "clone"}}
+	if err := p.CreateIssue(context.Background(), "title", "body"); err != nil {
+		t.Errorf("CreateIssue() error = %v", err)
+	}
+}
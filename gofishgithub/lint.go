@@ -1,6 +1,7 @@
 package gofishgithub
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -8,102 +9,163 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 
 	"github.com/dustin/go-humanize"
 	"github.com/fishworks/gofish"
-	"github.com/fishworks/gofish/pkg/home"
+	"github.com/gofish-bot/gofish-bot/cache"
+	"github.com/gofish-bot/gofish-bot/download"
+	"github.com/gofish-bot/gofish-bot/executable"
+	"github.com/gofish-bot/gofish-bot/lint"
 	"github.com/gofish-bot/gofish-bot/log"
 	"github.com/gofish-bot/gofish-bot/models"
 	"github.com/mholt/archiver/v3"
 )
 
-func (p *GoFish) Lint(app *models.Application) error {
+// Lint checks the food of the application rendered in the workspace
+func (p *GoFish) Lint(app *models.Application) (*lint.Report, error) {
 
-	bytes, err := ioutil.ReadFile("/usr/local/gofish/tmp/github.com/fmotrifork/fish-food/Food/" + app.Name + ".lua")
+	bytes, err := p.Workspace.ReadFood(app.Name)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	return p.lint(app.Name, string(bytes))
+	return p.lint(app.Name, string(bytes), app.LintRules, app.SmokeTest, app.SmokeArgs), nil
 }
 
-func (p *GoFish) LintString(name, content string) error {
+// LintString checks the food content with the rules
+func (p *GoFish) LintString(name, content string, rules lint.Rules) *lint.Report {
 
-	return p.lint(name, content)
+	return p.lint(name, content, rules, false, nil)
 }
 
-func (p *GoFish) lint(name, content string) error {
-
-	f, err := p.GetAsFood(content)
+// LintDir lints every food in the Food directory of a fish-food checkout,
+// up to parallel foods at the same time. The reports are sorted by food.
+func (p *GoFish) LintDir(dir string, rules lint.Rules, parallel int) ([]*lint.Report, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "Food", "*.lua"))
 	if err != nil {
-		return fmt.Errorf("Converting to food (%s) failed: %v", name, err)
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no foods found in %s", filepath.Join(dir, "Food"))
+	}
+	if parallel < 1 {
+		parallel = 1
 	}
 
-	errs := f.Lint()
-	if len(errs) > 0 {
-		e := ""
-		for _, err := range errs {
-			e += err.Error() + "\n"
-		}
-		return fmt.Errorf("Linting failed: %s \n - '%v'", name, e)
+	reports := make([]*lint.Report, len(files))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < parallel; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				name := strings.TrimSuffix(filepath.Base(files[i]), ".lua")
+				content, err := ioutil.ReadFile(files[i])
+				if err != nil {
+					reports[i] = lint.NewReport(name, rules)
+					reports[i].Addf(lint.RuleParse, "", "", "%v", err)
+					continue
+				}
+				log.L.Infof("Linting %s", name)
+				reports[i] = p.LintString(name, string(content), rules)
+			}
+		}()
+	}
+	for i := range files {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	return reports, nil
+}
+
+// lint checks the food and installs every package. When smoke is set the
+// linux/amd64 binary is also run with the smoke arguments.
+func (p *GoFish) lint(name, content string, rules lint.Rules, smoke bool, smokeArgs []string) *lint.Report {
+	report := lint.NewReport(name, rules)
+
+	f, err := p.GetAsFood(content)
+	if err != nil {
+		report.Addf(lint.RuleParse, "", "", "converting to food failed: %v", err)
+		return report
 	}
-	log.L.Debugf("Lint ok: %s", name)
+	report.Version = f.Version
 
 	for _, pkg := range f.Packages {
-		u, err := url.Parse(pkg.URL)
-		if err != nil {
-			return fmt.Errorf("could not parse package URL '%s' as a URL: %v", pkg.URL, err)
+		entry := p.fetchPackage(report, pkg)
+		if entry == nil {
+			continue
 		}
 
-		cachedFilePath := filepath.Join(home.Cache(), fmt.Sprintf("%s-%s-%s-%s%s", f.Name, f.Version, pkg.OS, pkg.Arch, getExtension(u.Path)))
-		fi, err := os.Stat(cachedFilePath)
-		if err != nil {
-			return err
-		}
-		// get the size
-		size := fi.Size()
-		if size < 100000 {
-			return fmt.Errorf("Linting failed: %s \n - file %s is to small %s", name, cachedFilePath, humanize.Bytes(uint64(size)))
+		if entry.Size < rules.MinSize {
+			report.Addf(lint.RuleMinSize, pkg.OS, pkg.Arch, "file %s is to small %s", pkg.URL, humanize.Bytes(uint64(entry.Size)))
 		}
 
-		err = testInstall(f, pkg, cachedFilePath)
-		if err != nil {
-			return fmt.Errorf("Installing failed: %v", err)
-		}
+		smokePkg := smoke && rules.Enabled(lint.RuleSmokeTest) && canSmokeTest(pkg.OS, pkg.Arch)
+		p.testInstall(report, f, pkg, entry.Path, smokePkg, smokeArgs)
+		entry.Close()
 	}
-	log.L.Debugf("Install ok: %s", name)
 
-	// Check for bad number of packages last! This error may be ignored
-	if len(f.Packages) < 3 {
-		return fmt.Errorf("Linting failed: %s \n - %s", name, "Bad number of packages")
+	if len(f.Packages) < rules.MinPackages {
+		report.Addf(lint.RuleMinPackages, "", "", "Bad number of packages: %d, expected at least %d", len(f.Packages), rules.MinPackages)
 	}
 
-	return nil
+	log.L.Debugf("Lint done: %s", report)
+	return report
 }
 
-func testInstall(f *gofish.Food, pkg *gofish.Package, src string) error {
+// fetchPackage downloads the package into the download cache and verifies
+// it against the sha256 of the food. Failures are added to the report.
+func (p *GoFish) fetchPackage(report *lint.Report, pkg *gofish.Package) *cache.Entry {
+	if _, err := url.Parse(pkg.URL); err != nil {
+		report.Addf(lint.RuleDownload, pkg.OS, pkg.Arch, "could not parse package URL '%s' as a URL: %v", pkg.URL, err)
+		return nil
+	}
+
+	entry, err := download.Get(context.Background(), p.HTTPClient, p.Cache, pkg.URL)
+	if err != nil {
+		report.Addf(lint.RuleDownload, pkg.OS, pkg.Arch, "%v", err)
+		return nil
+	}
+
+	if !strings.EqualFold(entry.SHA256, pkg.SHA256) {
+		report.Addf(lint.RuleChecksum, pkg.OS, pkg.Arch, "shasum verify check failed: expected %s, downloaded %s", pkg.SHA256, entry.SHA256)
+		entry.Close()
+		return nil
+	}
+	return entry
+}
+
+// testInstall unpacks the package and checks its resources. Failures are
+// added to the report.
+func (p *GoFish) testInstall(report *lint.Report, f *gofish.Food, pkg *gofish.Package, src string, smoke bool, smokeArgs []string) {
 	log.L.Debugf("Running install test")
 
-	barrel := filepath.Join(home.Cache(), "barrel")
+	barrel := filepath.Join(p.Workspace.Dir, "barrel")
 	barrelDir := filepath.Join(barrel, f.Name, f.Version, pkg.OS, pkg.Arch)
 
 	u, err := url.Parse(pkg.URL)
 	if err != nil {
-		return fmt.Errorf("could not parse package URL '%s' as a URL: %v", pkg.URL, err)
+		report.Addf(lint.RuleInstall, pkg.OS, pkg.Arch, "could not parse package URL '%s' as a URL: %v", pkg.URL, err)
+		return
 	}
 
 	err = os.RemoveAll(barrelDir)
-	if err != nil {
-		return err
+	if err == nil {
+		err = os.MkdirAll(barrelDir, 0755)
 	}
-
-	if err := os.MkdirAll(barrelDir, 0755); err != nil && !os.IsExist(err) {
-		return err
+	if err != nil {
+		report.Addf(lint.RuleInstall, pkg.OS, pkg.Arch, "%v", err)
+		return
 	}
 
 	err = unarchiveOrCopy(src, barrelDir, u.Path)
 	if err != nil {
-		return fmt.Errorf("Linting failed: %s \n - Could not unarchive or copy: %s %v", f.Name, u.Path, err)
+		report.Addf(lint.RuleInstall, pkg.OS, pkg.Arch, "Could not unarchive or copy: %s %v", u.Path, err)
+		return
 	}
 
 	for _, r := range pkg.Resources {
@@ -113,11 +175,26 @@ func testInstall(f *gofish.Food, pkg *gofish.Package, src string) error {
 		resourcePath := filepath.Join(barrelDir, rPath)
 		resourceFileInfo, err := os.Stat(resourcePath)
 		if err != nil {
-			return err
+			report.Addf(lint.RuleInstall, pkg.OS, pkg.Arch, "resource %s not found in package", r.Path)
+			continue
 		}
 		fType := "file"
 		if resourceFileInfo.IsDir() {
 			fType = "dir"
+		} else if isExecutable(r) {
+			err = executable.Check(resourcePath, pkg.OS, pkg.Arch)
+			if err != nil {
+				report.Addf(lint.RuleBinaryFormat, pkg.OS, pkg.Arch, "%v", err)
+				continue
+			}
+			if smoke {
+				err = smokeTest(resourcePath, smokeArgs, f.Version)
+				if err != nil {
+					report.Addf(lint.RuleSmokeTest, pkg.OS, pkg.Arch, "%s: %v", r.Path, err)
+					continue
+				}
+				log.L.Debugf("Smoke test ok: %s", f.Name)
+			}
 		}
 		log.L.Debugf("%10s %7s %s %d bytes %s %s",
 			pkg.OS, pkg.Arch,
@@ -127,15 +204,25 @@ func testInstall(f *gofish.Food, pkg *gofish.Package, src string) error {
 			resourceFileInfo.Name(),
 		)
 	}
-	return nil
+}
+
+// isExecutable reports whether the resource is a program, either made
+// executable or installed into bin like the .exe files on windows
+func isExecutable(r *gofish.Resource) bool {
+	installPath := strings.ReplaceAll(r.InstallPath, "\\", "/")
+	return r.Executable || strings.HasPrefix(installPath, "bin/")
 }
 
 // From github.com/fishworks/gofish@v0.13.0/food.go
+// The archive format is detected from the url, as cached files are not named
+// after the package.
 func unarchiveOrCopy(src, dest, urlPath string) error {
 
 	// check and see if it can be unarchived by archiver
-	if _, err := archiver.ByExtension(src); err == nil {
-		return archiver.Unarchive(src, dest)
+	if format, err := archiver.ByExtension(urlPath); err == nil {
+		if u, ok := format.(archiver.Unarchiver); ok {
+			return u.Unarchive(src, dest)
+		}
 	}
 
 	in, err := os.Open(src)
@@ -152,13 +239,3 @@ func unarchiveOrCopy(src, dest, urlPath string) error {
 	_, err = io.Copy(out, in)
 	return err
 }
-
-// From github.com/fishworks/gofish@v0.13.0/food.go
-func getExtension(path string) string {
-	urlParts := strings.Split(path, "/")
-	parts := strings.Split(urlParts[len(urlParts)-1], ".")
-	if len(parts) < 2 {
-		return filepath.Ext(path)
-	}
-	return "." + strings.Join([]string{parts[len(parts)-2], parts[len(parts)-1]}, ".")
-}
@@ -0,0 +1,226 @@
+package gofishgithub
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/gofish-bot/gofish-bot/cache"
+	"github.com/gofish-bot/gofish-bot/lint"
+	"github.com/gofish-bot/gofish-bot/workspace"
+)
+
+func lintFood(baseURL, sha string, platforms []string, resource string) string {
+	packages := ""
+	for _, platform := range platforms {
+		packages += fmt.Sprintf(`
+    {
+      os = "%s",
+      arch = "amd64",
+      url = "%s/%s/tool",
+      sha256 = "%s",
+      resources = {
+        {
+          path = "%s",
+          installpath = "bin/tool",
+          executable = true
+        }
+      }
+    },`, platform, baseURL, platform, sha, resource)
+	}
+	return fmt.Sprintf(`local name = "tool"
+local version = "1.0.0"
+
+food = {
+  name = name,
+  description = "A tool",
+  homepage = "https://example.com",
+  version = version,
+  packages = {%s
+  }
+}
+`, packages)
+}
+
+func TestGoFish_LintString(t *testing.T) {
+	binary := []byte("#!/bin/sh\necho 1.0.0\n" + strings.Repeat("#", 100001))
+	sha := fmt.Sprintf("%x", sha256.Sum256(binary))
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/missing/") {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(binary)
+	}))
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "gofish-bot-lint")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	downloadCache, err := cache.New(dir+"/cache", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	foodWorkspace, err := workspace.New(dir + "/workspace")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := &GoFish{
+		HTTPClient: server.Client(),
+		Cache:      downloadCache,
+		Workspace:  foodWorkspace,
+	}
+
+	all := []string{"darwin", "freebsd", "linux"}
+	defaults := lint.DefaultRules()
+	minPackages := 1
+	relaxed, err := defaults.Apply(&lint.Config{MinPackages: &minPackages, MinSize: "10B"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name      string
+		content   string
+		rules     lint.Rules
+		wantRules []string
+	}{
+		{
+			name:    "ok",
+			content: lintFood(server.URL, sha, all, "tool"),
+			rules:   defaults,
+		},
+		{
+			name:      "not a food",
+			content:   "food = ",
+			rules:     defaults,
+			wantRules: []string{lint.RuleParse},
+		},
+		{
+			name:      "sha mismatch",
+			content:   lintFood(server.URL, strings.Repeat("0", 64), all, "tool"),
+			rules:     defaults,
+			wantRules: []string{lint.RuleChecksum, lint.RuleChecksum, lint.RuleChecksum},
+		},
+		{
+			name:      "dead url",
+			content:   lintFood(server.URL, sha, []string{"darwin", "linux", "missing"}, "tool"),
+			rules:     defaults,
+			wantRules: []string{lint.RuleDownload},
+		},
+		{
+			name:      "missing resource",
+			content:   lintFood(server.URL, sha, all, "bin/tool"),
+			rules:     defaults,
+			wantRules: []string{lint.RuleInstall, lint.RuleInstall, lint.RuleInstall},
+		},
+		{
+			name:      "script for windows",
+			content:   lintFood(server.URL, sha, []string{"darwin", "linux", "windows"}, "tool"),
+			rules:     defaults,
+			wantRules: []string{lint.RuleBinaryFormat},
+		},
+		{
+			name:    "relaxed rules",
+			content: lintFood(server.URL, sha, []string{"linux"}, "tool"),
+			rules:   relaxed,
+		},
+		{
+			name:      "bad number of packages",
+			content:   lintFood(server.URL, sha, []string{"linux"}, "tool"),
+			rules:     defaults,
+			wantRules: []string{lint.RuleMinPackages},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := p.LintString("tool", tt.content, tt.rules)
+			rules := []string{}
+			for _, f := range report.Findings {
+				rules = append(rules, f.Rule)
+			}
+			if strings.Join(rules, ",") != strings.Join(tt.wantRules, ",") {
+				t.Errorf("LintString() findings = %s, want rules %v", report, tt.wantRules)
+			}
+		})
+	}
+}
+
+func TestGoFish_LintDir(t *testing.T) {
+	binary := []byte("#!/bin/sh\necho 1.0.0\n" + strings.Repeat("#", 100001))
+	sha := fmt.Sprintf("%x", sha256.Sum256(binary))
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(binary)
+	}))
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "gofish-bot-lint-dir")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	downloadCache, err := cache.New(dir+"/cache", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	foodWorkspace, err := workspace.New(dir + "/workspace")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := &GoFish{
+		HTTPClient: server.Client(),
+		Cache:      downloadCache,
+		Workspace:  foodWorkspace,
+	}
+
+	fishFood := dir + "/fish-food"
+	if err := os.MkdirAll(fishFood+"/Food", 0755); err != nil {
+		t.Fatal(err)
+	}
+	foods := map[string]string{
+		"tool":    lintFood(server.URL, sha, []string{"darwin", "freebsd", "linux"}, "tool"),
+		"broken":  "food = {",
+		"partial": lintFood(server.URL, sha, []string{"linux"}, "tool"),
+	}
+	for name, content := range foods {
+		if err := ioutil.WriteFile(fishFood+"/Food/"+name+".lua", []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	reports, err := p.LintDir(fishFood, lint.DefaultRules(), 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []struct {
+		food   string
+		failed bool
+	}{
+		{"broken", true},
+		{"partial", true},
+		{"tool", false},
+	}
+	if len(reports) != len(want) {
+		t.Fatalf("LintDir() returned %d reports, want %d", len(reports), len(want))
+	}
+	for i, w := range want {
+		if reports[i].Food != w.food || reports[i].Failed() != w.failed {
+			t.Errorf("LintDir()[%d] = %s, want %s failed %v", i, reports[i], w.food, w.failed)
+		}
+	}
+
+	if _, err := p.LintDir(dir, lint.DefaultRules(), 2); err == nil {
+		t.Errorf("LintDir() without foods did not fail")
+	}
+}
@@ -0,0 +1,404 @@
+package gofishgithub
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/text"
+)
+
+// The release notes are parsed as CommonMark, and only the text is
+// rewritten: mentions, references and urls in text, the destinations of
+// links and autolinks. Code spans, code blocks and HTML are kept as is. The
+// edits are spliced into the source, so the rest of the markdown is kept
+// byte for byte.
+
+// mdEdit replaces the source from start to end
+type mdEdit struct {
+	start, end  int
+	replacement string
+}
+
+// cleanMarkdown escapes @mentions, references to #PRs/#Issues and GH-PRs,
+// links and urls outside of code, so the pull request does not notify anyone
+// or link back to the upstream project
+func cleanMarkdown(markdown string) string {
+	source := []byte(markdown)
+	doc := goldmark.DefaultParser().Parse(text.NewReader(source))
+
+	c := &mdCleaner{source: markdown}
+	c.walk(doc)
+
+	sort.SliceStable(c.edits, func(i, j int) bool { return c.edits[i].start < c.edits[j].start })
+	var b strings.Builder
+	pos := 0
+	for _, e := range c.edits {
+		if e.start < pos {
+			continue
+		}
+		b.WriteString(markdown[pos:e.start])
+		b.WriteString(e.replacement)
+		pos = e.end
+	}
+	b.WriteString(markdown[pos:])
+	return b.String()
+}
+
+// mdCleaner collects the edits of the nodes in document order. cursor is
+// the end of the source covered by the nodes visited so far, the syntax of
+// links, images and autolinks is searched from there.
+type mdCleaner struct {
+	source string
+	edits  []mdEdit
+	cursor int
+	// textStart and textEnd are the run of adjacent text not cleaned yet
+	textStart, textEnd int
+	// inCode counts the open <code> and <pre> tags of inline HTML
+	inCode int
+}
+
+func (c *mdCleaner) walk(n ast.Node) {
+	switch n := n.(type) {
+	case *ast.FencedCodeBlock, *ast.CodeBlock, *ast.HTMLBlock:
+		return
+	case *ast.Text:
+		c.addText(n.Segment.Start, n.Segment.Stop)
+		return
+	case *ast.String:
+		return
+	case *ast.CodeSpan:
+		c.flushText()
+		c.skip(n)
+		return
+	case *ast.RawHTML:
+		c.flushText()
+		for i := 0; i < n.Segments.Len(); i++ {
+			segment := n.Segments.At(i)
+			if i == 0 {
+				c.countCodeTags(c.source[segment.Start:segment.Stop])
+			}
+			c.cursor = segment.Stop
+		}
+		return
+	case *ast.AutoLink:
+		c.flushText()
+		label := string(n.Label([]byte(c.source)))
+		if i := strings.Index(c.source[c.cursor:], "<"+label+">"); i >= 0 {
+			start := c.cursor + i
+			c.cursor = start + len(label) + 2
+			c.add(start, c.cursor, escapeURL(label))
+		}
+		return
+	case *ast.Image:
+		// Images are kept as is, they do not notify anyone
+		c.flushText()
+		if open := strings.Index(c.source[c.cursor:], "!["); open >= 0 {
+			c.cursor += open + 2
+			c.skip(n)
+			if close := strings.IndexByte(c.source[c.cursor:], ']'); close >= 0 {
+				c.cursor += close
+				c.cursor = c.linkEnd(n)
+			}
+		}
+		return
+	case *ast.Link:
+		// The link is replaced by its text followed by the destination
+		c.flushText()
+		open := strings.IndexByte(c.source[c.cursor:], '[')
+		if open < 0 {
+			break
+		}
+		open += c.cursor
+		c.add(open, open+1, "")
+		c.cursor = open + 1
+		c.walkChildren(n)
+		c.flushText()
+		close := strings.IndexByte(c.source[c.cursor:], ']')
+		if close < 0 {
+			return
+		}
+		c.cursor += close
+		end := c.linkEnd(n)
+		c.add(c.cursor, end, " ("+escapeURL(string(n.Destination))+")")
+		c.cursor = end
+		return
+	}
+
+	if n.Type() == ast.TypeBlock {
+		c.flushText()
+	}
+	c.walkChildren(n)
+	if n.Type() == ast.TypeBlock {
+		c.flushText()
+	}
+}
+
+func (c *mdCleaner) walkChildren(n ast.Node) {
+	for child := n.FirstChild(); child != nil; child = child.NextSibling() {
+		c.walk(child)
+	}
+}
+
+// skip moves the cursor behind the text of the children of n, without
+// cleaning it
+func (c *mdCleaner) skip(n ast.Node) {
+	ast.Walk(n, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if t, ok := n.(*ast.Text); ok && entering && t.Segment.Stop > c.cursor {
+			c.cursor = t.Segment.Stop
+		}
+		return ast.WalkContinue, nil
+	})
+}
+
+func (c *mdCleaner) add(start, end int, replacement string) {
+	c.edits = append(c.edits, mdEdit{start: start, end: end, replacement: replacement})
+}
+
+// addText extends the run of text, or starts a new one when the text does
+// not follow it directly
+func (c *mdCleaner) addText(start, end int) {
+	if start != c.textEnd || c.textEnd == c.textStart {
+		c.flushText()
+		c.textStart = start
+	}
+	c.textEnd = end
+	c.cursor = end
+}
+
+// flushText cleans the run of text
+func (c *mdCleaner) flushText() {
+	if c.textEnd > c.textStart && c.inCode == 0 {
+		c.edits = append(c.edits, cleanText(c.source, c.textStart, c.textEnd)...)
+	}
+	c.textStart, c.textEnd = c.cursor, c.cursor
+}
+
+// countCodeTags tracks the <code> and <pre> tags of inline HTML, text in
+// them is code
+func (c *mdCleaner) countCodeTags(html string) {
+	tag := strings.ToLower(html)
+	switch {
+	case strings.HasPrefix(tag, "<code") || strings.HasPrefix(tag, "<pre"):
+		c.inCode++
+	case (strings.HasPrefix(tag, "</code") || strings.HasPrefix(tag, "</pre")) && c.inCode > 0:
+		c.inCode--
+	}
+}
+
+// linkEnd returns the end of the link or image, whose text is closed by the
+// ] at the cursor. The destination and title are skipped for inline links
+// and the label for full reference links.
+func (c *mdCleaner) linkEnd(n ast.Node) int {
+	s := c.source
+	i := c.cursor + 1
+	end := i
+	switch {
+	case i < len(s) && s[i] == '(':
+		end = inlineLinkEnd(s, i)
+	case i < len(s) && s[i] == '[':
+		if j := closingBracket(s, i+1); j >= 0 {
+			end = j + 1
+		}
+	}
+	// The link ends where the following text starts
+	if next, ok := n.NextSibling().(*ast.Text); ok && next.Segment.Start >= i && next.Segment.Start < end {
+		end = next.Segment.Start
+	}
+	return end
+}
+
+// inlineLinkEnd returns the end of the destination and title of an inline
+// link in parentheses starting at i
+func inlineLinkEnd(s string, i int) int {
+	j := skipSpace(s, i+1)
+	if j < len(s) && s[j] == '<' {
+		for j++; j < len(s) && s[j] != '>'; j++ {
+			if s[j] == '\\' {
+				j++
+			}
+		}
+		j++
+	} else {
+		depth := 0
+	dest:
+		for ; j < len(s); j++ {
+			switch s[j] {
+			case '\\':
+				j++
+			case '(':
+				depth++
+			case ')':
+				if depth == 0 {
+					break dest
+				}
+				depth--
+			case ' ', '\t', '\r', '\n':
+				break dest
+			}
+		}
+	}
+
+	j = skipSpace(s, j)
+	if j < len(s) && (s[j] == '"' || s[j] == '\'' || s[j] == '(') {
+		closer := s[j]
+		if closer == '(' {
+			closer = ')'
+		}
+		for j++; j < len(s) && s[j] != closer; j++ {
+			if s[j] == '\\' {
+				j++
+			}
+		}
+		j = skipSpace(s, j+1)
+	}
+	if j < len(s) && s[j] == ')' {
+		return j + 1
+	}
+	return i
+}
+
+func skipSpace(s string, i int) int {
+	for i < len(s) && strings.IndexByte(" \t\r\n", s[i]) >= 0 {
+		i++
+	}
+	return i
+}
+
+// closingBracket returns the index of the ] closing a link label from i
+func closingBracket(s string, i int) int {
+	for ; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case ']':
+			return i
+		}
+	}
+	return -1
+}
+
+// escapeURL breaks up the url so it is not linked
+func escapeURL(url string) string {
+	url = strings.ReplaceAll(url, "/", "<span/>/")
+	return strings.ReplaceAll(url, ".", "<span/>.")
+}
+
+// cleanText returns the edits escaping the urls, mentions and references in
+// the text from start to end of s
+func cleanText(s string, start, end int) []mdEdit {
+	edits := []mdEdit{}
+	text := s[:end]
+	for i := start; i < end; {
+		var edit *mdEdit
+		switch c := text[i]; {
+		case c == '@' && !isWordBefore(text, i):
+			edit = parseMention(text, i)
+		case c == '#' && !(i > 0 && text[i-1] == '&'):
+			// &#123; is a character reference
+			edit = parseReference(text, i)
+		case (c == 'G' || c == 'g') && !isWordBefore(text, i):
+			edit = parseGHReference(text, i)
+		case (c == 'h' || c == 'w') && !isWordBefore(text, i):
+			edit = parseURL(text, i)
+		}
+		if edit == nil {
+			i++
+			continue
+		}
+		edits = append(edits, *edit)
+		i = edit.end
+	}
+	return edits
+}
+
+// parseURL parses an url GitHub links without markdown syntax, starting with
+// http://, https:// or www.
+func parseURL(s string, i int) *mdEdit {
+	rest := s[i:]
+	if !strings.HasPrefix(rest, "http://") && !strings.HasPrefix(rest, "https://") && !strings.HasPrefix(rest, "www.") {
+		return nil
+	}
+	end := strings.IndexAny(rest, " \t\r\n<")
+	if end < 0 {
+		end = len(rest)
+	}
+	url := rest[:end]
+
+	// Trailing punctuation and unbalanced parentheses are not part of the url
+	for url != "" {
+		last := url[len(url)-1]
+		if strings.IndexByte("?!.,:*_~'\"", last) >= 0 {
+			url = url[:len(url)-1]
+		} else if last == ')' && strings.Count(url, "(") < strings.Count(url, ")") {
+			url = url[:len(url)-1]
+		} else {
+			break
+		}
+	}
+	if !strings.Contains(url, ".") {
+		return nil
+	}
+	return &mdEdit{start: i, end: i + len(url), replacement: escapeURL(url)}
+}
+
+// parseMention parses a mention of a user or team, like @gofish-bot
+func parseMention(s string, i int) *mdEdit {
+	n := 0
+	for i+1+n < len(s) && n < 39 {
+		c := s[i+1+n]
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || n > 0 && c == '-') {
+			break
+		}
+		n++
+	}
+	if n == 0 {
+		return nil
+	}
+	return &mdEdit{start: i, end: i + 1 + n, replacement: "@<!-- -->" + s[i+1:i+1+n]}
+}
+
+// parseReference parses a reference to an issue or pull request, like #123 or
+// fishworks/fish-food#123
+func parseReference(s string, i int) *mdEdit {
+	n := 0
+	for i+1+n < len(s) && isDigit(s[i+1+n]) {
+		n++
+	}
+	if n == 0 {
+		return nil
+	}
+	return &mdEdit{start: i, end: i + 1 + n, replacement: "#<!-- -->" + s[i+1:i+1+n]}
+}
+
+// parseGHReference parses a reference to an issue or pull request of the same
+// repository, like GH-123, in any case
+func parseGHReference(s string, i int) *mdEdit {
+	if len(s) < i+3 || !strings.EqualFold(s[i:i+3], "GH-") {
+		return nil
+	}
+	n := 0
+	for i+3+n < len(s) && isDigit(s[i+3+n]) {
+		n++
+	}
+	if n == 0 {
+		return nil
+	}
+	return &mdEdit{start: i, end: i + 3 + n, replacement: s[i:i+3] + "<!-- -->" + s[i+3:i+3+n]}
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// isWordBefore reports whether a letter or digit is in front of i
+func isWordBefore(s string, i int) bool {
+	if i == 0 {
+		return false
+	}
+	r, _ := utf8.DecodeLastRuneInString(s[:i])
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_'
+}
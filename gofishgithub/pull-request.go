@@ -2,24 +2,30 @@ package gofishgithub
 
 import (
 	"context"
+	"crypto/sha256"
 	"fmt"
-	"io/ioutil"
-	"regexp"
+	"net/http"
 	"strings"
 
 	"github.com/gobuffalo/envy"
 	"golang.org/x/oauth2"
 
-	"github.com/pkg/errors"
-
+	"github.com/gofish-bot/gofish-bot/batch"
+	"github.com/gofish-bot/gofish-bot/cache"
 	"github.com/gofish-bot/gofish-bot/log"
 	"github.com/gofish-bot/gofish-bot/models"
-	"github.com/google/go-github/v32/github"
+	"github.com/gofish-bot/gofish-bot/publish"
+	"github.com/gofish-bot/gofish-bot/workspace"
 	ghApi "github.com/google/go-github/v32/github"
 )
 
 type GoFish struct {
-	Client      *ghApi.Client
+	Client     *ghApi.Client
+	HTTPClient *http.Client
+	Cache      *cache.Cache
+	Workspace  *workspace.Workspace
+	// Publisher publishes the foods, by default to the fork of the bot on GitHub
+	Publisher   publish.Publisher
 	BotOrg      string
 	FoodRepo    string
 	FoodOrg     string
@@ -27,7 +33,9 @@ type GoFish struct {
 	AuthorEmail string
 }
 
-func CreateClient(ctx context.Context) *ghApi.Client {
+// CreateClient creates an authenticated GitHub client sending its requests
+// through httpClient
+func CreateClient(ctx context.Context, httpClient *http.Client) *ghApi.Client {
 	githubToken, err := envy.MustGet("GITHUB_TOKEN")
 	if err != nil {
 		log.G(ctx).Fatalf("Error getting Github token: %v", err)
@@ -36,160 +44,223 @@ func CreateClient(ctx context.Context) *ghApi.Client {
 	ts := oauth2.StaticTokenSource(
 		&oauth2.Token{AccessToken: githubToken},
 	)
+	ctx = context.WithValue(ctx, oauth2.HTTPClient, httpClient)
 	tc := oauth2.NewClient(ctx, ts)
+	tc.Timeout = httpClient.Timeout
 	return ghApi.NewClient(tc)
 }
 
-// https://godoc.org/github.com/google/go-github/github#example-RepositoriesService-CreateFile
-func (p *GoFish) CreatePullRequest(ctx context.Context, application *models.Application, fileContent []byte) error {
-	branch := fmt.Sprintf("%s-%s", application.Name, application.ReleaseName)
-	var err error
+// CreateReadClient creates a GitHub client for reading public repositories
+// and releases, authenticated when GITHUB_TOKEN is set
+func CreateReadClient(ctx context.Context, httpClient *http.Client) *ghApi.Client {
+	if envy.Get("GITHUB_TOKEN", "") == "" {
+		log.G(ctx).Debugf("GITHUB_TOKEN is not set, using the GitHub API without authentication")
+		return ghApi.NewClient(httpClient)
+	}
+	return CreateClient(ctx, httpClient)
+}
 
-	err = p.createNewBranch(ctx, application, branch)
+// CreatePullRequests opens a pull request per application, or per batch of
+// applications when batching is configured, for the foods rendered in the
+// workspace
+func (p *GoFish) CreatePullRequests(ctx context.Context, applications []*models.Application, settings models.BatchSettings) {
+	batches, err := batch.Group(applications, settings.GroupBy, settings.MaxSize)
 	if err != nil {
-		return err
+		log.G(ctx).Warnf("Could not batch pull requests: %v", err)
+		return
 	}
-	body := fmt.Sprintf("Updating package %s to release %s.", application.Name, application.ReleaseName)
-	if application.ReleaseDescription != "" {
-
-		// Escape mentions and links to PRs/Issues
-		releaseDescription := cleanMarkdown(application.ReleaseDescription)
 
-		body = fmt.Sprintf("Updating package %s to release %s. \n\n# Release info \n\n %s", application.Name, application.ReleaseName, releaseDescription)
-	}
-	if application.CurrentVersion != "" {
-		err = p.updateFile(ctx, application, fileContent, branch)
-		if err != nil {
-			return err
+	for _, b := range batches {
+		if len(b.Applications) == 1 {
+			application := b.Applications[0]
+			log.G(ctx).Infof("## Creating Pullrequest for %s version %s", application.Name, application.Version)
+			content, err := p.Workspace.ReadFood(application.Name)
+			if err != nil {
+				log.G(ctx).Warn(err)
+				continue
+			}
+			err = p.CreatePullRequest(ctx, application, content)
+			if err != nil {
+				log.G(ctx).Warnf("Failed creating PR: %v", err)
+			}
+			continue
 		}
-	} else {
-		body = fmt.Sprintf("Creating package %s in version %s.", application.Name, application.ReleaseName)
 
-		err = p.createFile(ctx, application, fileContent, branch)
+		log.G(ctx).Infof("## Creating Pullrequest for batch %s of %d foods", b.Name, len(b.Applications))
+		err := p.CreateBatchPullRequest(ctx, b.Name, b.Applications)
 		if err != nil {
-			return err
+			log.G(ctx).Warnf("Failed creating PR: %v", err)
 		}
 	}
-	err = p.newPullRequest(ctx, application, branch, body)
-	if err != nil {
-		return err
-	}
-	return nil
 }
 
-func (p *GoFish) updateFile(ctx context.Context, application *models.Application, fileContent []byte, branch string) error {
-	log.G(ctx).Debug("Updating File")
+// CreatePullRequest publishes the food on a new branch and opens a pull
+// request for it
+func (p *GoFish) CreatePullRequest(ctx context.Context, application *models.Application, fileContent []byte) error {
+	branch := fmt.Sprintf("%s-%s", application.Name, application.ReleaseName)
+	publisher := p.publisher()
 
-	getOpts := &github.RepositoryContentGetOptions{Ref: branch}
-	res, _, _, err := p.Client.Repositories.GetContents(ctx, p.BotOrg, p.FoodRepo, fmt.Sprintf("Food/%s.lua", application.Name), getOpts)
+	err := publisher.CreateBranch(ctx, branch)
+	if err != nil {
+		return err
+	}
+	title := fmt.Sprintf("%s %s", application.Name, application.Version)
+	err = publisher.WriteFile(ctx, branch, fmt.Sprintf("Food/%s.lua", application.Name), fileContent, title)
 	if err != nil {
 		return err
 	}
 
-	opts := &github.RepositoryContentFileOptions{
-		Message:   github.String(fmt.Sprintf("%s %s", application.Name, application.Version)),
-		Content:   fileContent,
-		Branch:    github.String(branch),
-		Author:    &github.CommitAuthor{Name: github.String(p.AuthorName), Email: github.String(p.AuthorEmail)},
-		Committer: &github.CommitAuthor{Name: github.String(p.AuthorName), Email: github.String(p.AuthorEmail)},
-		SHA:       github.String(res.GetSHA()),
+	body := fmt.Sprintf("Updating package %s to release %s.", application.Name, application.ReleaseName)
+	summary := checksumSummary(application) + lintSummary(application)
+	if application.CurrentVersion == "" {
+		body = fmt.Sprintf("Creating package %s in version %s.", application.Name, application.ReleaseName)
+	} else {
+		// The release notes of every release since the current version,
+		// with mentions and links to PRs/Issues escaped
+		const releaseInfo = "\n\n# Release info\n\n"
+		notes := changelog(application, maxBodySize-len(body)-len(releaseInfo)-len(summary))
+		if notes != "" {
+			body += releaseInfo + notes
+		}
 	}
-	_, _, err = p.Client.Repositories.UpdateFile(ctx, p.BotOrg, p.FoodRepo, fmt.Sprintf("Food/%s.lua", application.Name), opts)
+	body += summary
+
+	link, err := publisher.OpenPullRequest(ctx, publish.PullRequest{Branch: branch, Title: title, Body: body})
 	if err != nil {
 		return err
 	}
 
+	log.G(ctx).Infof("PR created: %s", link)
 	return nil
 }
 
-func (p *GoFish) createFile(ctx context.Context, application *models.Application, fileContent []byte, branch string) error {
-	log.G(ctx).Debug("Creating new File")
+// CreateBatchPullRequest publishes the foods of the applications, rendered in
+// the workspace, on one branch with a commit per food and opens a single pull
+// request for all of them
+func (p *GoFish) CreateBatchPullRequest(ctx context.Context, name string, applications []*models.Application) error {
+	publisher := p.publisher()
 
-	opts := &github.RepositoryContentFileOptions{
-		Message:   github.String(fmt.Sprintf("%s %s", application.Name, application.Version)),
-		Content:   fileContent,
-		Branch:    github.String(branch),
-		Author:    &github.CommitAuthor{Name: github.String(p.AuthorName), Email: github.String(p.AuthorEmail)},
-		Committer: &github.CommitAuthor{Name: github.String(p.AuthorName), Email: github.String(p.AuthorEmail)},
+	// The branch is named after the updates, so the same batch is only
+	// proposed once
+	h := sha256.New()
+	for _, app := range applications {
+		fmt.Fprintf(h, "%s %s\n", app.Name, app.Version)
+	}
+	branch := fmt.Sprintf("batch-%s-%x", name, h.Sum(nil)[:4])
+
+	err := publisher.CreateBranch(ctx, branch)
+	if err != nil {
+		return err
+	}
+	for _, app := range applications {
+		content, err := p.Workspace.ReadFood(app.Name)
+		if err != nil {
+			return err
+		}
+		err = publisher.WriteFile(ctx, branch, fmt.Sprintf("Food/%s.lua", app.Name), content, fmt.Sprintf("%s %s", app.Name, app.Version))
+		if err != nil {
+			return err
+		}
 	}
-	_, _, err := p.Client.Repositories.UpdateFile(ctx, p.BotOrg, p.FoodRepo, fmt.Sprintf("Food/%s.lua", application.Name), opts)
+
+	title := fmt.Sprintf("Update %d foods (%s)", len(applications), name)
+	link, err := publisher.OpenPullRequest(ctx, publish.PullRequest{Branch: branch, Title: title, Body: batchSummary(applications, maxBodySize)})
 	if err != nil {
 		return err
 	}
 
+	log.G(ctx).Infof("PR created: %s", link)
 	return nil
 }
 
-func (p *GoFish) createNewBranch(ctx context.Context, application *models.Application, branch string) error {
-	log.G(ctx).Debugf("Creating new Branch %s\n", branch)
+// batchSummary tables the updates of a batch pull request, followed by the
+// checksums and lint report of every food, in at most size characters. Rows
+// and details that do not fit are left out with a note.
+func batchSummary(applications []*models.Application, size int) string {
+	omitted := func(n int, what string) string {
+		if n == 0 {
+			return ""
+		}
+		return fmt.Sprintf("\n%d %s omitted\n", n, what)
+	}
 
-	ref, _, err := p.Client.Git.GetRef(ctx, p.BotOrg, p.FoodRepo, "refs/heads/main")
-	if err != nil {
-		return errors.Wrapf(err, "Error creating ref %s", ref)
+	blocks := []string{}
+	for _, app := range applications {
+		details := checksumSummary(app) + lintSummary(app)
+		if details != "" {
+			blocks = append(blocks, fmt.Sprintf("\n<details><summary>%s %s</summary>%s\n</details>\n", app.Name, app.Version, details))
+		}
 	}
 
-	_, _, err = p.Client.Git.CreateRef(ctx, p.BotOrg, p.FoodRepo, &github.Reference{
-		Ref:    github.String(fmt.Sprintf("refs/heads/%s", branch)),
-		Object: ref.GetObject(),
-	})
-	if err != nil {
-		return err
+	var b strings.Builder
+	fmt.Fprintf(&b, "Updating %d packages.\n\n| Food | From | To | Release | Lint |\n| --- | --- | --- | --- | --- |\n", len(applications))
+	for i, app := range applications {
+		release := app.ReleaseName
+		if app.ReleaseLink != "" {
+			release = fmt.Sprintf("[%s](%s)", app.ReleaseName, app.ReleaseLink)
+		}
+		lintStatus := ""
+		if app.LintReport != nil {
+			lintStatus = "ok"
+			if n := len(app.LintReport.Findings); n > 0 {
+				lintStatus = fmt.Sprintf("%d findings", n)
+			}
+		}
+		row := fmt.Sprintf("| %s | %s | %s | %s | %s |\n", app.Name, app.CurrentVersion, app.Version, release, lintStatus)
+		// Room is left for the note on what follows the row
+		rest := omitted(len(applications)-i-1, "more packages")
+		if i == len(applications)-1 {
+			rest = omitted(len(blocks), "more package details")
+		}
+		if b.Len()+len(row)+len(rest) > size {
+			b.WriteString(omitted(len(applications)-i, "more packages"))
+			return b.String()
+		}
+		b.WriteString(row)
 	}
-	return nil
-}
 
-func (p *GoFish) newPullRequest(ctx context.Context, application *models.Application, branch, body string) error {
-	log.G(ctx).Debugf("Sending pull request\n")
-	newPR := &github.NewPullRequest{
-		Title:               github.String(fmt.Sprintf("%s %s", application.Name, application.Version)),
-		Head:                github.String(p.BotOrg + ":" + branch),
-		Base:                github.String("main"),
-		Body:                github.String(body),
-		MaintainerCanModify: github.Bool(true),
+	for i, block := range blocks {
+		if b.Len()+len(block)+len(omitted(len(blocks)-i-1, "more package details")) > size {
+			b.WriteString(omitted(len(blocks)-i, "more package details"))
+			break
+		}
+		b.WriteString(block)
 	}
+	return b.String()
+}
 
-	pr, res, err := p.Client.PullRequests.Create(context.Background(), p.FoodOrg, p.FoodRepo, newPR)
-	if err != nil {
-		r, _ := ioutil.ReadAll(res.Response.Body)
-		defer res.Response.Body.Close()
-		log.G(ctx).Warnf("%s", r)
-		return err
+// publisher returns the Publisher, by default publishing to the fork of the
+// bot through the GitHub client
+func (p *GoFish) publisher() publish.Publisher {
+	if p.Publisher == nil {
+		p.Publisher = &publish.GitHub{
+			Client:   p.Client,
+			Owner:    p.BotOrg,
+			Upstream: p.FoodOrg,
+			Repo:     p.FoodRepo,
+			Author:   publish.Author{Name: p.AuthorName, Email: p.AuthorEmail},
+		}
 	}
+	return p.Publisher
+}
 
-	log.G(ctx).Infof("PR created: %s\n", pr.GetHTMLURL())
-	return nil
+// checksumSummary lists the sha256 of every package and whether it was
+// published upstream and verified against the downloaded content
+func checksumSummary(application *models.Application) string {
+	if len(application.Assets) == 0 {
+		return ""
+	}
+	summary := "\n\n# Checksums\n\n| OS | Arch | sha256 | Source |\n| --- | --- | --- | --- |\n"
+	for _, asset := range application.Assets {
+		summary += fmt.Sprintf("| %s | %s | `%s` | %s |\n", asset.Os, asset.Arch, asset.Sha256, asset.ChecksumSource)
+	}
+	return summary
 }
 
-// cleanMarkdown escapes @mentions and links to #PRs/#Issues
-func cleanMarkdown(markdown string) string {
-	// Escape issue links
-	var re = regexp.MustCompile(`#([0-9]+)`)
-	markdown = re.ReplaceAllString(markdown, `#<!-- -->$1`)
-
-	// Excape mentions
-	re = regexp.MustCompile(`@([a-zA-Z\d](?:[a-zA-Z\d\-]){0,38})`)
-	markdown = re.ReplaceAllString(markdown, `@<!-- -->$1`)
-
-	// Remove Markdown Link syntax
-	re = regexp.MustCompile(`\[([^\[]+)\]\((.*)\)`)
-	markdown = re.ReplaceAllString(markdown, "$2")
-
-	// Escape everything that looks like a url
-	// TODO This regex also replaces urls inside markdown script tags..
-	re = regexp.MustCompile(`[(http(s)?):\/\/(www\.)?a-zA-Z0-9@:%._\+~#=]{2,256}\.[a-z]{2,6}\b([-a-zA-Z0-9@:%_\+.~#?&//=]*)`)
-	allLinks := re.FindAllStringSubmatch(markdown, -1)
-	for _, linkRes := range allLinks {
-		// Escape all slashes
-		cleanUrl := strings.ReplaceAll(linkRes[0], "/", "<span/>/")
-		// Escape all dots
-		cleanUrl = strings.ReplaceAll(cleanUrl, ".", "<span/>.")
-		markdown = strings.ReplaceAll(markdown, linkRes[0], cleanUrl)
-	}
-
-	// TODO We may want to add this?
-	// Replaces github repo links
-	// re = regexp.MustCompile(`([a-zA-Z\d-]+)\/([a-zA-Z\d-]+)`)
-	// markdown = re.ReplaceAllString(markdown, "$1<span/>/$2")
-
-	return markdown
+// lintSummary renders the lint report of the rendered food
+func lintSummary(application *models.Application) string {
+	if application.LintReport == nil {
+		return ""
+	}
+	return "\n\n# Lint\n\n" + application.LintReport.Markdown()
 }
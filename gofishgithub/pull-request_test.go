@@ -1,6 +1,22 @@
 package gofishgithub
 
-import "testing"
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/gofish-bot/gofish-bot/lint"
+	"github.com/gofish-bot/gofish-bot/models"
+	"github.com/gofish-bot/gofish-bot/publish"
+	"github.com/gofish-bot/gofish-bot/workspace"
+)
 
 func Test_cleanReleaseDescription(t *testing.T) {
 	type args struct {
@@ -14,39 +30,174 @@ func Test_cleanReleaseDescription(t *testing.T) {
 		{
 			name: "Does not hurt headers",
 			markdown: `# Header 1
-			## Header 2
-			### Header 1`,
+## Header 2
+### Header 1`,
 			want: `# Header 1
-			## Header 2
-			### Header 1`,
+## Header 2
+### Header 1`,
 		},
 		{
 			name: "Replaces issue numbers",
 			markdown: `# Header 1
-			Fixes issue #123 which was no good...!`,
+Fixes issue #123 which was no good...!`,
 			want: `# Header 1
-			Fixes issue #<!-- -->123 which was no good...!`,
+Fixes issue #<!-- -->123 which was no good...!`,
 		},
 		{
 			name: "Replaces mentions",
 			markdown: `# Header 1
-			@gofish-bot does stuff! @GoFiSh-BOT`,
+@gofish-bot does stuff! @GoFiSh-BOT`,
 			want: `# Header 1
-			@<!-- -->gofish-bot does stuff! @<!-- -->GoFiSh-BOT`,
+@<!-- -->gofish-bot does stuff! @<!-- -->GoFiSh-BOT`,
 		},
 		{
-			name: "Replaces links",
+			name: "Replaces links, keeping the text",
 			markdown: `# Header 1
-			[Here i am](https://github.com/gofish-bot/gofish-bot/) `,
+[Here i am](https://github.com/gofish-bot/gofish-bot/) `,
 			want: `# Header 1
-			https:<span/>/<span/>/github<span/>.com<span/>/gofish-bot<span/>/gofish-bot<span/>/ `,
+Here i am (https:<span/>/<span/>/github<span/>.com<span/>/gofish-bot<span/>/gofish-bot<span/>/) `,
 		},
 		{
 			name: "Replaces links without markdown syntax",
 			markdown: `# Header 1
-			Github does link magic.. https://github.com/gofish-bot/gofish-bot/ `,
+Github does link magic.. https://github.com/gofish-bot/gofish-bot/ `,
 			want: `# Header 1
-			Github does link magic.. https:<span/>/<span/>/github<span/>.com<span/>/gofish-bot<span/>/gofish-bot<span/>/ `,
+Github does link magic.. https:<span/>/<span/>/github<span/>.com<span/>/gofish-bot<span/>/gofish-bot<span/>/ `,
+		},
+		{
+			name:     "Replaces cross repository references",
+			markdown: `Fixed in fishworks/fish-food#42 and gofish-bot/gofish-bot#7.`,
+			want:     `Fixed in fishworks/fish-food#<!-- -->42 and gofish-bot/gofish-bot#<!-- -->7.`,
+		},
+		{
+			name:     "Replaces GH references",
+			markdown: "Fixes GH-123 (see gh-7, TGH-5 and `GH-9`)\n## GH-42",
+			want:     "Fixes GH-<!-- -->123 (see gh-<!-- -->7, TGH-5 and `GH-9`)\n## GH-<!-- -->42",
+		},
+		{
+			name:     "Replaces references in headings",
+			markdown: "## Fixes #12 by @someone\n",
+			want:     "## Fixes #<!-- -->12 by @<!-- -->someone\n",
+		},
+		{
+			name:     "Does not hurt heading markers without space",
+			markdown: "#1 is the first issue\n#hashtag",
+			want:     "#<!-- -->1 is the first issue\n#hashtag",
+		},
+		{
+			name:     "Keeps code spans",
+			markdown: "Run `curl https://example.com/install.sh | sh -s @latest #1` now, thanks @someone",
+			want:     "Run `curl https://example.com/install.sh | sh -s @latest #1` now, thanks @<!-- -->someone",
+		},
+		{
+			name:     "Keeps code spans with double backticks",
+			markdown: "``a ` @b`` @c",
+			want:     "``a ` @b`` @<!-- -->c",
+		},
+		{
+			name:     "Unmatched backticks are text",
+			markdown: "`@a and ``#1",
+			want:     "`@<!-- -->a and ``#<!-- -->1",
+		},
+		{
+			name:     "Keeps fenced code blocks",
+			markdown: "Install:\n```sh\ncurl -L https://github.com/gofish-bot/gofish-bot/releases # see #1\n@user\n```\nThanks @user",
+			want:     "Install:\n```sh\ncurl -L https://github.com/gofish-bot/gofish-bot/releases # see #1\n@user\n```\nThanks @<!-- -->user",
+		},
+		{
+			name:     "Keeps tilde fenced code blocks",
+			markdown: "~~~~\n@user #1\n~~~\n#2\n~~~~\n#3",
+			want:     "~~~~\n@user #1\n~~~\n#2\n~~~~\n#<!-- -->3",
+		},
+		{
+			name:     "Unclosed fenced code blocks run to the end",
+			markdown: "```\n@user\n#1",
+			want:     "```\n@user\n#1",
+		},
+		{
+			name:     "Keeps indented code blocks",
+			markdown: "Run:\n\n    @user fixed #1\n\nThanks @user",
+			want:     "Run:\n\n    @user fixed #1\n\nThanks @<!-- -->user",
+		},
+		{
+			name:     "Keeps fenced code blocks in blockquotes",
+			markdown: "> ```\n> curl https://example.com @user #1\n> ```\n> by @user",
+			want:     "> ```\n> curl https://example.com @user #1\n> ```\n> by @<!-- -->user",
+		},
+		{
+			name:     "Keeps code blocks in list items",
+			markdown: "- Install:\n\n  ```\n  curl https://example.com @user #1\n  ```\n- Run:\n\n      @user #2\n- Fixed #3",
+			want:     "- Install:\n\n  ```\n  curl https://example.com @user #1\n  ```\n- Run:\n\n      @user #2\n- Fixed #<!-- -->3",
+		},
+		{
+			name:     "Keeps HTML blocks",
+			markdown: "<pre>curl https://example.com/x @user</pre>\n\nThanks @user",
+			want:     "<pre>curl https://example.com/x @user</pre>\n\nThanks @<!-- -->user",
+		},
+		{
+			name:     "Keeps inline code tags",
+			markdown: "Run <code>curl https://example.com/x @user</code> @user",
+			want:     "Run <code>curl https://example.com/x @user</code> @<!-- -->user",
+		},
+		{
+			name:     "Keeps character references",
+			markdown: "&#123; and &#x7B; fix #1",
+			want:     "&#123; and &#x7B; fix #<!-- -->1",
+		},
+		{
+			name:     "Replaces mentions in link text",
+			markdown: "[link with @bob](https://x.com)",
+			want:     "link with @<!-- -->bob (https:<span/>/<span/>/x<span/>.com)",
+		},
+		{
+			name:     "Replaces reference links",
+			markdown: "See [the docs][docs] and [docs].\n\n[docs]: https://example.com/docs",
+			want:     "See the docs (https:<span/>/<span/>/example<span/>.com<span/>/docs) and docs (https:<span/>/<span/>/example<span/>.com<span/>/docs).\n\n[docs]: https://example.com/docs",
+		},
+		{
+			name:     "Keeps images",
+			markdown: `![screenshot of @user](https://github.com/gofish-bot/gofish-bot/raw/main/images/robot.jpeg) by @user`,
+			want:     `![screenshot of @user](https://github.com/gofish-bot/gofish-bot/raw/main/images/robot.jpeg) by @<!-- -->user`,
+		},
+		{
+			name:     "Replaces links with parentheses and titles",
+			markdown: `See [the docs](https://example.com/a_(b) "Docs") and [#1](https://example.com/1).`,
+			want:     `See the docs (https:<span/>/<span/>/example<span/>.com<span/>/a_(b)) and #<!-- -->1 (https:<span/>/<span/>/example<span/>.com<span/>/1).`,
+		},
+		{
+			name:     "Replaces autolinks",
+			markdown: `Docs at <https://example.com/docs>, not <b>bold</b>`,
+			want:     `Docs at https:<span/>/<span/>/example<span/>.com<span/>/docs, not <b>bold</b>`,
+		},
+		{
+			name:     "Replaces www links",
+			markdown: `Visit www.example.com/docs.`,
+			want:     `Visit www<span/>.example<span/>.com<span/>/docs.`,
+		},
+		{
+			name:     "Leaves punctuation after urls",
+			markdown: `(see https://example.com/a?b=1), or http://example.com!`,
+			want:     `(see https:<span/>/<span/>/example<span/>.com<span/>/a?b=1), or http:<span/>/<span/>/example<span/>.com!`,
+		},
+		{
+			name:     "Does not replace email addresses",
+			markdown: `Mail security@example.com about #1`,
+			want:     `Mail security@example.com about #<!-- -->1`,
+		},
+		{
+			name:     "Keeps version numbers",
+			markdown: `Upgrade from 1.2.0 to v1.5.3...!`,
+			want:     `Upgrade from 1.2.0 to v1.5.3...!`,
+		},
+		{
+			name:     "Replaces team mentions",
+			markdown: `cc @fishworks/maintainers`,
+			want:     `cc @<!-- -->fishworks/maintainers`,
+		},
+		{
+			name:     "Keeps windows line endings",
+			markdown: "# Changes\r\n```\r\n@user\r\n```\r\n@user\r\n",
+			want:     "# Changes\r\n```\r\n@user\r\n```\r\n@<!-- -->user\r\n",
 		},
 		// We may want to add this?
 		// {
@@ -63,3 +214,341 @@ func Test_cleanReleaseDescription(t *testing.T) {
 		})
 	}
 }
+
+// recordingPublisher records what is published instead of publishing it
+type recordingPublisher struct {
+	branches []string
+	files    map[string]string
+	prs      []publish.PullRequest
+}
+
+func (r *recordingPublisher) BaseBranch(ctx context.Context) (string, error) {
+	return "main", nil
+}
+
+func (r *recordingPublisher) CreateBranch(ctx context.Context, branch string) error {
+	r.branches = append(r.branches, branch)
+	return nil
+}
+
+func (r *recordingPublisher) WriteFile(ctx context.Context, branch, path string, content []byte, message string) error {
+	r.files[branch+":"+path] = string(content)
+	return nil
+}
+
+func (r *recordingPublisher) OpenPullRequest(ctx context.Context, pr publish.PullRequest) (string, error) {
+	r.prs = append(r.prs, pr)
+	return "pr-" + pr.Branch, nil
+}
+
+func TestGoFish_CreatePullRequest(t *testing.T) {
+	tests := []struct {
+		name           string
+		currentVersion string
+		wantBody       string
+	}{
+		{"update", "0.9.0", "Updating package tool to release v1.0.0."},
+		{"create", "", "Creating package tool in version v1.0.0."},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			publisher := &recordingPublisher{files: map[string]string{}}
+			p := &GoFish{Publisher: publisher}
+			app := &models.Application{
+				Name:           "tool",
+				ReleaseName:    "v1.0.0",
+				Version:        "1.0.0",
+				CurrentVersion: tt.currentVersion,
+			}
+
+			if err := p.CreatePullRequest(context.Background(), app, []byte("food")); err != nil {
+				t.Fatal(err)
+			}
+			if len(publisher.branches) != 1 || publisher.branches[0] != "tool-v1.0.0" {
+				t.Errorf("branches = %v, want [tool-v1.0.0]", publisher.branches)
+			}
+			if got := publisher.files["tool-v1.0.0:Food/tool.lua"]; got != "food" {
+				t.Errorf("Food/tool.lua = %q, want %q", got, "food")
+			}
+			if len(publisher.prs) != 1 {
+				t.Fatalf("opened %d pull requests, want 1", len(publisher.prs))
+			}
+			pr := publisher.prs[0]
+			if pr.Title != "tool 1.0.0" || !strings.HasPrefix(pr.Body, tt.wantBody) {
+				t.Errorf("pull request = %q %q, want %q %q", pr.Title, pr.Body, "tool 1.0.0", tt.wantBody)
+			}
+		})
+	}
+}
+
+// readingPublisher serves foods from the files of its base branch
+type readingPublisher struct {
+	recordingPublisher
+	base string
+}
+
+func (r *readingPublisher) BaseBranch(ctx context.Context) (string, error) {
+	return r.base, nil
+}
+
+func (r *readingPublisher) ReadFile(ctx context.Context, ref, path string) ([]byte, error) {
+	content, ok := r.files[ref+":"+path]
+	if !ok {
+		return nil, fmt.Errorf("%s not found in %s", path, ref)
+	}
+	return []byte(content), nil
+}
+
+func (r *readingPublisher) ListFiles(ctx context.Context, ref, dir string) ([]string, error) {
+	names := []string{}
+	for key := range r.files {
+		name := strings.TrimPrefix(key, ref+":"+dir+"/")
+		if name != key && !strings.Contains(name, "/") {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func TestGoFish_GetFood(t *testing.T) {
+	publisher := &readingPublisher{base: "master", recordingPublisher: recordingPublisher{files: map[string]string{
+		"master:Food/tool.lua": `local name = "tool"
+local version = "1.2.3"
+food = {name=name, version=version, packages={}}`,
+		"main:Food/tool.lua": `food = {name="tool", version="0.0.1", packages={}}`,
+	}}}
+	p := &GoFish{Publisher: publisher}
+
+	food, err := p.GetFood(context.Background(), "tool")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if food.Version != "1.2.3" {
+		t.Errorf("GetFood() version = %s, want 1.2.3 from the base branch", food.Version)
+	}
+
+	version, err := p.GetCurrentVersion(context.Background(), models.DesiredApp{Name: "tool"})
+	if err != nil || version != "1.2.3" {
+		t.Errorf("GetCurrentVersion() = %s, %v, want 1.2.3", version, err)
+	}
+}
+
+func TestGoFish_ListFoods(t *testing.T) {
+	publisher := &readingPublisher{base: "master", recordingPublisher: recordingPublisher{files: map[string]string{
+		"master:Food/tool.lua":       "",
+		"master:Food/other.lua":      "",
+		"master:Food/README.md":      "",
+		"master:Food/nested/x.lua":   "",
+		"main:Food/only-on-main.lua": "",
+	}}}
+	p := &GoFish{Publisher: publisher}
+
+	names, err := p.ListFoods(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"other", "tool"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("ListFoods() = %v, want %v", names, want)
+	}
+}
+
+func TestGoFish_CreateBatchPullRequest(t *testing.T) {
+	foodWorkspace, err := workspace.New("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer foodWorkspace.Close()
+
+	apps := []*models.Application{
+		{Name: "kubectl", ReleaseName: "v1.20.0", ReleaseLink: "https://github.com/kubernetes/kubernetes/releases/tag/v1.20.0", Version: "1.20.0", CurrentVersion: "1.19.0"},
+		{Name: "kind", ReleaseName: "v0.10.0", Version: "0.10.0", CurrentVersion: "0.9.0", LintReport: &lint.Report{Findings: []lint.Finding{{Rule: lint.RuleMinSize}}}},
+	}
+	for _, app := range apps {
+		if err := foodWorkspace.WriteFood(app.Name, []byte(app.Name+" food")); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	publisher := &recordingPublisher{files: map[string]string{}}
+	p := &GoFish{Publisher: publisher, Workspace: foodWorkspace}
+	if err := p.CreateBatchPullRequest(context.Background(), "kubernetes", apps); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(publisher.branches) != 1 || !strings.HasPrefix(publisher.branches[0], "batch-kubernetes-") {
+		t.Fatalf("branches = %v, want one batch-kubernetes- branch", publisher.branches)
+	}
+	branch := publisher.branches[0]
+	for _, app := range apps {
+		if got := publisher.files[branch+":Food/"+app.Name+".lua"]; got != app.Name+" food" {
+			t.Errorf("Food/%s.lua = %q", app.Name, got)
+		}
+	}
+	if len(publisher.prs) != 1 {
+		t.Fatalf("opened %d pull requests, want 1", len(publisher.prs))
+	}
+	pr := publisher.prs[0]
+	if pr.Title != "Update 2 foods (kubernetes)" {
+		t.Errorf("title = %q", pr.Title)
+	}
+	for _, row := range []string{
+		"| kubectl | 1.19.0 | 1.20.0 | [v1.20.0](https://github.com/kubernetes/kubernetes/releases/tag/v1.20.0) |  |",
+		"| kind | 0.9.0 | 0.10.0 | v0.10.0 | 1 findings |",
+	} {
+		if !strings.Contains(pr.Body, row) {
+			t.Errorf("body does not contain %q:\n%s", row, pr.Body)
+		}
+	}
+}
+
+func TestGoFish_CreatePullRequest_git(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gofish-bot-pull-request")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	origin := filepath.Join(dir, "origin")
+	clone := filepath.Join(dir, "clone")
+
+	run := func(dir string, args ...string) string {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+		cmd.Env = append(os.Environ(),
+			"GIT_AUTHOR_NAME=test", "GIT_AUTHOR_EMAIL=test@example.com",
+			"GIT_COMMITTER_NAME=test", "GIT_COMMITTER_EMAIL=test@example.com",
+		)
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+		return strings.TrimSpace(string(out))
+	}
+
+	if err := os.MkdirAll(filepath.Join(origin, "Food"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	food := `food = { name = "tool", version = "0.9.0" }`
+	if err := ioutil.WriteFile(filepath.Join(origin, "Food", "tool.lua"), []byte(food), 0644); err != nil {
+		t.Fatal(err)
+	}
+	run(origin, "init", "-q", "-b", "main")
+	run(origin, "add", "-A")
+	run(origin, "commit", "-q", "-m", "init")
+	run(dir, "clone", "-q", origin, clone)
+	// Without GitHub credentials the commits are authored by the identity
+	// configured in the clone
+	run(clone, "config", "user.name", "local")
+	run(clone, "config", "user.email", "local@example.com")
+
+	ctx := context.Background()
+	p := &GoFish{Publisher: &publish.Git{Dir: clone}}
+	current, err := p.GetFood(ctx, "tool")
+	if err != nil {
+		t.Fatal(err)
+	}
+	app := &models.Application{
+		Name:           "tool",
+		ReleaseName:    "v1.0.0",
+		Version:        "1.0.0",
+		CurrentVersion: current.Version,
+		ReleaseNotes:   []models.ReleaseNote{{Name: "v1.0.0", Version: "1.0.0", Description: "Fixes #1"}},
+	}
+	if err := p.CreatePullRequest(ctx, app, []byte(`food = { name = "tool", version = "1.0.0" }`)); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"show", "tool-v1.0.0:Food/tool.lua"}, `food = { name = "tool", version = "1.0.0" }`},
+		{[]string{"log", "--format=%s %an <%ae>", "tool-v1.0.0"}, "tool 1.0.0 local <local@example.com>\ninit test <test@example.com>"},
+		// The checked out branch and working tree are left alone
+		{[]string{"rev-parse", "--abbrev-ref", "HEAD"}, "main"},
+		{[]string{"status", "--porcelain"}, ""},
+	}
+	for _, tt := range tests {
+		if got := run(clone, tt.args...); got != tt.want {
+			t.Errorf("git %v = %q, want %q", tt.args, got, tt.want)
+		}
+	}
+
+	pr, err := ioutil.ReadFile(filepath.Join(clone, ".git", "pull-requests", "tool-v1.0.0.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "# tool 1.0.0\n\nMerge tool-v1.0.0 into main\n\nUpdating package tool to release v1.0.0.\n\n# Release info\n\n"
+	if !strings.HasPrefix(string(pr), want) || !strings.Contains(string(pr), "#<!-- -->1") {
+		t.Errorf("pull request = %q, want prefix %q", pr, want)
+	}
+}
+
+func Test_batchSummary_size(t *testing.T) {
+	apps := []*models.Application{}
+	for i := 0; i < 2000; i++ {
+		apps = append(apps, &models.Application{
+			Name:           fmt.Sprintf("tool-%d", i),
+			ReleaseName:    "v1.0.0",
+			Version:        "1.0.0",
+			CurrentVersion: "0.9.0",
+			Assets:         []models.Asset{{Os: "linux", Arch: "amd64", Sha256: strings.Repeat("0", 64)}},
+		})
+	}
+
+	tests := []struct {
+		name     string
+		apps     []*models.Application
+		size     int
+		contains string
+	}{
+		{"fits", apps[:2], maxBodySize, "<summary>tool-1 1.0.0</summary>"},
+		{"details omitted", apps[:1000], maxBodySize, "more package details omitted"},
+		{"rows omitted", apps, maxBodySize, "more packages omitted"},
+		{"small", apps[:2], 170, "1 more packages omitted"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := batchSummary(tt.apps, tt.size)
+			if len(got) > tt.size {
+				t.Errorf("batchSummary() is %d characters, want at most %d", len(got), tt.size)
+			}
+			if !strings.Contains(got, tt.contains) {
+				t.Errorf("batchSummary() does not contain %q:\n%s", tt.contains, got)
+			}
+		})
+	}
+}
+
+func TestGoFish_CreatePullRequests(t *testing.T) {
+	foodWorkspace, err := workspace.New("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer foodWorkspace.Close()
+
+	apps := []*models.Application{
+		{Name: "kubectl", ReleaseName: "v1.20.0", Version: "1.20.0", Label: "kubernetes"},
+		{Name: "kind", ReleaseName: "v0.10.0", Version: "0.10.0", Label: "kubernetes"},
+		{Name: "tool", ReleaseName: "v1.0.0", Version: "1.0.0"},
+	}
+	for _, app := range apps {
+		if err := foodWorkspace.WriteFood(app.Name, []byte(app.Name+" food")); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	publisher := &recordingPublisher{files: map[string]string{}}
+	p := &GoFish{Publisher: publisher, Workspace: foodWorkspace}
+	p.CreatePullRequests(context.Background(), apps, models.BatchSettings{GroupBy: "label"})
+
+	if len(publisher.prs) != 2 {
+		t.Fatalf("opened %d pull requests, want 2", len(publisher.prs))
+	}
+	if got := publisher.prs[0].Title; got != "Update 2 foods (kubernetes)" {
+		t.Errorf("first pull request = %q, want the kubernetes batch", got)
+	}
+	if got := publisher.files["tool-v1.0.0:Food/tool.lua"]; got != "tool food" {
+		t.Errorf("Food/tool.lua = %q, want tool food", got)
+	}
+}
@@ -0,0 +1,68 @@
+package gofishgithub
+
+import (
+	"context"
+
+	"github.com/blang/semver"
+	ghApi "github.com/google/go-github/v32/github"
+
+	"github.com/gofish-bot/gofish-bot/log"
+	"github.com/gofish-bot/gofish-bot/models"
+)
+
+// maxReleasePages limits the pages of releases listed for the changelog of
+// one application
+const maxReleasePages = 10
+
+// ListReleases lists the releases of the repository, newest first, until the
+// page with the release of version since. Only the first page is listed when
+// since is not a version. version extracts the version from a release tag.
+func (p *GoFish) ListReleases(ctx context.Context, org, repo, since string, version func(tag string) string) ([]*ghApi.RepositoryRelease, error) {
+	current, err := semver.ParseTolerant(since)
+	known := err == nil
+
+	releases := []*ghApi.RepositoryRelease{}
+	opt := &ghApi.ListOptions{PerPage: 100}
+	for page := 1; ; page++ {
+		list, resp, err := p.Client.Repositories.ListReleases(ctx, org, repo, opt)
+		if err != nil {
+			return nil, err
+		}
+		releases = append(releases, list...)
+		if !known || resp.NextPage == 0 || reachedRelease(list, current, version) {
+			return releases, nil
+		}
+		if page == maxReleasePages {
+			log.G(ctx).Warnf("Only the newest %d releases of %s/%s are listed, older release notes are omitted", len(releases), org, repo)
+			return releases, nil
+		}
+		opt.Page = resp.NextPage
+	}
+}
+
+// reachedRelease reports whether the list contains a release at or before
+// the current version
+func reachedRelease(list []*ghApi.RepositoryRelease, current semver.Version, version func(tag string) string) bool {
+	for _, release := range list {
+		v, err := semver.ParseTolerant(version(release.GetTagName()))
+		if err == nil && v.LTE(current) {
+			return true
+		}
+	}
+	return false
+}
+
+// ReleaseNotes returns the notes of the releases. version extracts the
+// version from a release tag.
+func ReleaseNotes(releases []*ghApi.RepositoryRelease, version func(tag string) string) []models.ReleaseNote {
+	notes := []models.ReleaseNote{}
+	for _, release := range releases {
+		notes = append(notes, models.ReleaseNote{
+			Name:        release.GetTagName(),
+			Version:     version(release.GetTagName()),
+			Link:        release.GetHTMLURL(),
+			Description: release.GetBody(),
+		})
+	}
+	return notes
+}
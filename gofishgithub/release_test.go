@@ -0,0 +1,87 @@
+package gofishgithub
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+
+	ghApi "github.com/google/go-github/v32/github"
+)
+
+func TestGoFish_ListReleases(t *testing.T) {
+	// Three pages of two releases, v1.5.0 to v1.0.0
+	tags := []string{"v1.5.0", "v1.4.0", "v1.3.0", "v1.2.0", "v1.1.0", "v1.0.0"}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		if page == 0 {
+			page = 1
+		}
+		if r.URL.Query().Get("per_page") != "100" {
+			t.Errorf("per_page = %s, want 100", r.URL.Query().Get("per_page"))
+		}
+		if page < 3 {
+			w.Header().Set("Link", fmt.Sprintf(`<%s%s?page=%d>; rel="next"`, "http://"+r.Host, r.URL.Path, page+1))
+		}
+		releases := []*ghApi.RepositoryRelease{}
+		for _, tag := range tags[(page-1)*2 : page*2] {
+			releases = append(releases, &ghApi.RepositoryRelease{TagName: ghApi.String(tag)})
+		}
+		json.NewEncoder(w).Encode(releases)
+	}))
+	defer server.Close()
+
+	client := ghApi.NewClient(server.Client())
+	client.BaseURL, _ = url.Parse(server.URL + "/")
+	p := &GoFish{Client: client}
+
+	tests := []struct {
+		name  string
+		since string
+		want  string
+	}{
+		{"unknown version", "", "v1.5.0 v1.4.0"},
+		{"newest release", "1.5.0", "v1.5.0 v1.4.0"},
+		{"second page", "1.2.0", "v1.5.0 v1.4.0 v1.3.0 v1.2.0"},
+		{"between releases", "1.2.5", "v1.5.0 v1.4.0 v1.3.0 v1.2.0"},
+		{"older than all releases", "0.9.0", "v1.5.0 v1.4.0 v1.3.0 v1.2.0 v1.1.0 v1.0.0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			releases, err := p.ListReleases(context.Background(), "org", "tool", tt.since, func(tag string) string { return tag })
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := []string{}
+			for _, release := range releases {
+				got = append(got, release.GetTagName())
+			}
+			if strings.Join(got, " ") != tt.want {
+				t.Errorf("ListReleases() = %v, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReleaseNotes(t *testing.T) {
+	releases := []*ghApi.RepositoryRelease{
+		{TagName: ghApi.String("tool-v1.1.0"), HTMLURL: ghApi.String("https://github.com/org/tool/releases/tag/tool-v1.1.0"), Body: ghApi.String("Fixes")},
+		{TagName: ghApi.String("tool-v1.0.0")},
+	}
+	notes := ReleaseNotes(releases, func(tag string) string { return strings.TrimPrefix(tag, "tool-v") })
+
+	if len(notes) != 2 {
+		t.Fatalf("ReleaseNotes() returned %d notes, want 2", len(notes))
+	}
+	if n := notes[0]; n.Name != "tool-v1.1.0" || n.Version != "1.1.0" || n.Link != releases[0].GetHTMLURL() || n.Description != "Fixes" {
+		t.Errorf("ReleaseNotes()[0] = %+v", n)
+	}
+	if n := notes[1]; n.Name != "tool-v1.0.0" || n.Version != "1.0.0" {
+		t.Errorf("ReleaseNotes()[1] = %+v", n)
+	}
+}
@@ -0,0 +1,62 @@
+package gofishgithub
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func Test_smokeTest(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("smoke tests only run on linux")
+	}
+
+	dir, err := ioutil.TempDir("", "gofish-bot-smoke-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	defer func(timeout time.Duration) { smokeTimeout = timeout }(smokeTimeout)
+	smokeTimeout = time.Second
+
+	os.Setenv("GOFISH_BOT_SECRET", "secret")
+	defer os.Unsetenv("GOFISH_BOT_SECRET")
+
+	tests := []struct {
+		name    string
+		script  string
+		args    []string
+		wantErr bool
+	}{
+		{name: "default args", script: `[ "$1" = "--version" ] && echo "tool version 1.2.3"`},
+		{name: "custom args", script: `[ "$1" = "version" ] && [ "$2" = "--short" ] && echo "v1.2.3"`, args: []string{"version", "--short"}},
+		{name: "version on stderr", script: `echo "tool 1.2.3" >&2`},
+		{name: "non zero exit with version", script: `echo "1.2.3"; exit 2`},
+		{name: "wrong version", script: `echo "tool version 1.2.2"`, wantErr: true},
+		{name: "crash", script: `echo "error while loading shared libraries: libc.so.6" >&2; exit 127`, wantErr: true},
+		{name: "timeout", script: `sleep 5; echo 1.2.3`, wantErr: true},
+		{name: "background child", script: `sleep 5 & echo 1.2.3`},
+		{name: "no secrets in env", script: `echo "$GOFISH_BOT_SECRET 1.2.3" | grep -q secret || echo 1.2.3`},
+		{name: "temporary home", script: `[ "$HOME" != "` + os.Getenv("HOME") + `" ] && [ "$PWD" = "$HOME" ] && echo 1.2.3`},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, string(rune('a'+i)))
+			if err := ioutil.WriteFile(path, []byte("#!/bin/sh\n"+tt.script+"\n"), 0644); err != nil {
+				t.Fatal(err)
+			}
+			start := time.Now()
+			err := smokeTest(path, tt.args, "1.2.3")
+			if (err != nil) != tt.wantErr {
+				t.Errorf("smokeTest() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if elapsed := time.Since(start); elapsed > 2*smokeTimeout {
+				t.Errorf("smokeTest() took %s, the timeout is %s", elapsed, smokeTimeout)
+			}
+		})
+	}
+}
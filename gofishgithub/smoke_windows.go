@@ -0,0 +1,14 @@
+package gofishgithub
+
+import (
+	"os/exec"
+)
+
+// setProcessGroup is not supported on windows, only the command itself is
+// killed
+func setProcessGroup(cmd *exec.Cmd) {}
+
+// killProcessGroup kills the started command
+func killProcessGroup(cmd *exec.Cmd) {
+	cmd.Process.Kill()
+}
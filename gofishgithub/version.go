@@ -3,17 +3,21 @@ package gofishgithub
 import (
 	"context"
 	"fmt"
+	"strings"
 
 	"github.com/gofish-bot/gofish-bot/models"
+	"github.com/gofish-bot/gofish-bot/publish"
 
 	"github.com/fishworks/gofish"
 	"github.com/google/go-github/v32/github"
-	"github.com/yuin/gluamapper"
-	lua "github.com/yuin/gopher-lua"
 )
 
 func (p *GoFish) GetCurrentVersion(ctx context.Context, app models.DesiredApp) (string, error) {
-	return p.getVersion(ctx, app, "main")
+	base, err := p.publisher().BaseBranch(ctx)
+	if err != nil {
+		return "", err
+	}
+	return p.getVersion(ctx, app, base)
 }
 
 func (p *GoFish) getVersion(ctx context.Context, app models.DesiredApp, ref string) (string, error) {
@@ -27,27 +31,69 @@ func (p *GoFish) getVersion(ctx context.Context, app models.DesiredApp, ref stri
 }
 
 func (p *GoFish) GetCurrentFood(ctx context.Context, appRepo string) (string, *gofish.Food, error) {
-	content, err := p.getContent(ctx, appRepo, "main")
+	base, err := p.publisher().BaseBranch(ctx)
 	if err != nil {
 		return "", nil, err
 	}
-	food, err := p.getFood(ctx, appRepo, "main")
+	content, err := p.getContent(ctx, appRepo, base)
+	if err != nil {
+		return "", nil, err
+	}
+	food, err := p.getFood(ctx, appRepo, base)
 	if err != nil {
 		return "", nil, err
 	}
 	return content, food, nil
 }
-func (p *GoFish) GetAsFood(content string) (*gofish.Food, error) {
-	l := lua.NewState()
-	defer l.Close()
-	if err := l.DoString(content); err != nil {
+
+// GetFood returns the current food of the app
+func (p *GoFish) GetFood(ctx context.Context, appName string) (*gofish.Food, error) {
+	base, err := p.publisher().BaseBranch(ctx)
+	if err != nil {
 		return nil, err
 	}
-	var food gofish.Food
-	if err := gluamapper.Map(l.GetGlobal("food").(*lua.LTable), &food); err != nil {
+	return p.getFood(ctx, appName, base)
+}
+
+// ListFoods returns the names of all foods in the food repository
+func (p *GoFish) ListFoods(ctx context.Context) ([]string, error) {
+	base, err := p.publisher().BaseBranch(ctx)
+	if err != nil {
 		return nil, err
 	}
-	return &food, nil
+
+	// Foods are listed in the repository they are published to
+	var files []string
+	if reader, ok := p.Publisher.(publish.Reader); ok {
+		files, err = reader.ListFiles(ctx, base, "Food")
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		getOpts := &github.RepositoryContentGetOptions{Ref: base}
+		_, dir, _, err := p.Client.Repositories.GetContents(ctx, p.FoodOrg, p.FoodRepo, "Food", getOpts)
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range dir {
+			if file.GetType() == "file" {
+				files = append(files, file.GetName())
+			}
+		}
+	}
+
+	names := []string{}
+	for _, file := range files {
+		if strings.HasSuffix(file, ".lua") {
+			names = append(names, strings.TrimSuffix(file, ".lua"))
+		}
+	}
+	return names, nil
+}
+
+// GetAsFood evaluates the lua content of a food in a sandbox
+func (p *GoFish) GetAsFood(content string) (*gofish.Food, error) {
+	return evalFood(content)
 }
 
 func (p *GoFish) getFood(ctx context.Context, appName string, ref string) (*gofish.Food, error) {
@@ -56,20 +102,17 @@ func (p *GoFish) getFood(ctx context.Context, appName string, ref string) (*gofi
 		return nil, err
 	}
 
-	l := lua.NewState()
-	defer l.Close()
-	if err := l.DoString(content); err != nil {
-		return nil, err
-	}
-	var food gofish.Food
-	if err := gluamapper.Map(l.GetGlobal("food").(*lua.LTable), &food); err != nil {
-		return nil, err
-	}
-	return &food, nil
+	return p.GetAsFood(content)
 }
 
 func (p *GoFish) getContent(ctx context.Context, appName string, ref string) (string, error) {
 
+	// Foods are read from the repository they are published to
+	if reader, ok := p.Publisher.(publish.Reader); ok {
+		content, err := reader.ReadFile(ctx, ref, fmt.Sprintf("Food/%s.lua", appName))
+		return string(content), err
+	}
+
 	getOpts := &github.RepositoryContentGetOptions{Ref: ref}
 	res, _, _, err := p.Client.Repositories.GetContents(ctx, p.FoodOrg, p.FoodRepo, fmt.Sprintf("Food/%s.lua", appName), getOpts)
 	if err != nil {
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gofish-bot/gofish-bot/httpclient"
+	"github.com/gofish-bot/gofish-bot/log"
+	"github.com/gofish-bot/gofish-bot/models"
+)
+
+// getHTTPClient creates the HTTP client shared by the GitHub client and all
+// downloads. Flags take precedence over the settings.
+func getHTTPClient(settings models.Settings, timeout string, retries int, proxy, caBundle string) *http.Client {
+	if timeout == "" {
+		timeout = settings.HTTP.Timeout
+	}
+	if retries == 0 {
+		retries = settings.HTTP.Retries
+	}
+	if proxy == "" {
+		proxy = settings.HTTP.Proxy
+	}
+	if caBundle == "" {
+		caBundle = settings.HTTP.CABundle
+	}
+
+	opts := httpclient.Options{
+		Retries:   retries,
+		Proxy:     proxy,
+		CABundle:  caBundle,
+		UserAgent: settings.HTTP.UserAgent,
+	}
+	if timeout != "" {
+		var err error
+		opts.Timeout, err = time.ParseDuration(timeout)
+		if err != nil {
+			log.L.Fatalf("Invalid http timeout '%s': %v", timeout, err)
+		}
+	}
+
+	client, err := httpclient.New(opts)
+	if err != nil {
+		log.L.Fatalf("Error creating http client: %v", err)
+	}
+	return client
+}
@@ -0,0 +1,174 @@
+package httpclient
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/gofish-bot/gofish-bot/log"
+)
+
+// DefaultUserAgent identifies the bot to GitHub and download hosts
+const DefaultUserAgent = "gofish-bot (+https://github.com/gofish-bot/gofish-bot)"
+
+// Options configures the HTTP client. The zero value gives a client with the
+// defaults below.
+type Options struct {
+	// Timeout limits each request, including reading the response body.
+	// Default 5m.
+	Timeout time.Duration
+	// Retries is the number of times a GET or HEAD request failing with a
+	// network error, a 5xx or a 429 response is retried. Default 3, negative
+	// disables retries.
+	Retries int
+	// RetryWait is the wait before the first retry, doubled for every
+	// following retry. Default 1s.
+	RetryWait time.Duration
+	// Proxy is the URL of the proxy for all requests. When empty the
+	// HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables are used.
+	Proxy string
+	// CABundle is the path to PEM encoded certificates trusted in addition to
+	// the system certificates
+	CABundle string
+	// UserAgent is sent with every request. Default DefaultUserAgent.
+	UserAgent string
+}
+
+// New creates an HTTP client from the options
+func New(opts Options) (*http.Client, error) {
+	if opts.Timeout == 0 {
+		opts.Timeout = 5 * time.Minute
+	}
+	if opts.Retries == 0 {
+		opts.Retries = 3
+	}
+	if opts.RetryWait == 0 {
+		opts.RetryWait = time.Second
+	}
+	if opts.UserAgent == "" {
+		opts.UserAgent = DefaultUserAgent
+	}
+
+	proxy := http.ProxyFromEnvironment
+	if opts.Proxy != "" {
+		proxyURL, err := url.Parse(opts.Proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy url '%s': %v", opts.Proxy, err)
+		}
+		proxy = http.ProxyURL(proxyURL)
+	}
+
+	tlsConfig := &tls.Config{}
+	if opts.CABundle != "" {
+		pool, err := certPool(opts.CABundle)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	transport := &http.Transport{
+		Proxy: proxy,
+		DialContext: (&net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		TLSClientConfig:       tlsConfig,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          100,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ResponseHeaderTimeout: time.Minute,
+		ExpectContinueTimeout: time.Second,
+	}
+
+	return &http.Client{
+		Timeout: opts.Timeout,
+		Transport: &retryTransport{
+			base:      transport,
+			retries:   opts.Retries,
+			wait:      opts.RetryWait,
+			userAgent: opts.UserAgent,
+		},
+	}, nil
+}
+
+func certPool(caBundle string) (*x509.CertPool, error) {
+	pem, err := ioutil.ReadFile(caBundle)
+	if err != nil {
+		return nil, fmt.Errorf("could not read CA bundle: %v", err)
+	}
+	pool, err := x509.SystemCertPool()
+	if err != nil || pool == nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificates found in CA bundle %s", caBundle)
+	}
+	return pool, nil
+}
+
+// retryTransport sets the user agent and retries requests failing with
+// transient errors, waiting exponentially longer between each attempt
+type retryTransport struct {
+	base      http.RoundTripper
+	retries   int
+	wait      time.Duration
+	userAgent string
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = cloneRequest(req)
+	req.Header.Set("User-Agent", t.userAgent)
+
+	wait := t.wait
+	for attempt := 0; ; attempt++ {
+		resp, err := t.base.RoundTrip(req)
+		if attempt >= t.retries || !retryable(req, resp, err) {
+			return resp, err
+		}
+
+		if err != nil {
+			log.L.Debugf("Retrying %s %s in %s: %v", req.Method, req.URL, wait, err)
+		} else {
+			log.L.Debugf("Retrying %s %s in %s: %s", req.Method, req.URL, wait, resp.Status)
+			resp.Body.Close()
+		}
+
+		select {
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		case <-time.After(wait):
+		}
+		wait *= 2
+	}
+}
+
+// retryable reports whether the request failed with a transient error and
+// can safely be sent again
+func retryable(req *http.Request, resp *http.Response, err error) bool {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		return false
+	}
+	if req.Context().Err() != nil {
+		return false
+	}
+	if err != nil {
+		return true
+	}
+	return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+}
+
+func cloneRequest(req *http.Request) *http.Request {
+	clone := req.WithContext(req.Context())
+	clone.Header = make(http.Header, len(req.Header))
+	for k, v := range req.Header {
+		clone.Header[k] = append([]string(nil), v...)
+	}
+	return clone
+}
@@ -0,0 +1,86 @@
+package httpclient
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNew_Retries(t *testing.T) {
+	tests := []struct {
+		name         string
+		method       string
+		statuses     []int
+		wantStatus   int
+		wantAttempts int
+	}{
+		{name: "ok", method: http.MethodGet, statuses: []int{200}, wantStatus: 200, wantAttempts: 1},
+		{name: "retry 5xx", method: http.MethodGet, statuses: []int{502, 503, 200}, wantStatus: 200, wantAttempts: 3},
+		{name: "retry rate limit", method: http.MethodGet, statuses: []int{429, 200}, wantStatus: 200, wantAttempts: 2},
+		{name: "give up", method: http.MethodGet, statuses: []int{500, 500, 500}, wantStatus: 500, wantAttempts: 3},
+		{name: "no retry 404", method: http.MethodGet, statuses: []int{404, 200}, wantStatus: 404, wantAttempts: 1},
+		{name: "no retry post", method: http.MethodPost, statuses: []int{500, 200}, wantStatus: 500, wantAttempts: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attempts := 0
+			userAgent := ""
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				userAgent = r.UserAgent()
+				w.WriteHeader(tt.statuses[attempts])
+				attempts++
+			}))
+			defer server.Close()
+
+			client, err := New(Options{Retries: 2, RetryWait: time.Millisecond})
+			if err != nil {
+				t.Fatal(err)
+			}
+			req, err := http.NewRequest(tt.method, server.URL, strings.NewReader(""))
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp, err := client.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+
+			if resp.StatusCode != tt.wantStatus {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+			if attempts != tt.wantAttempts {
+				t.Errorf("attempts = %d, want %d", attempts, tt.wantAttempts)
+			}
+			if userAgent != DefaultUserAgent {
+				t.Errorf("user agent = %s, want %s", userAgent, DefaultUserAgent)
+			}
+		})
+	}
+}
+
+func TestNew_Timeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+	}))
+	defer server.Close()
+
+	client, err := New(Options{Timeout: 50 * time.Millisecond, Retries: -1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Get(server.URL); err == nil {
+		t.Errorf("Get() did not time out")
+	}
+}
+
+func TestNew_InvalidOptions(t *testing.T) {
+	if _, err := New(Options{Proxy: "://proxy"}); err == nil {
+		t.Errorf("New() accepted an invalid proxy")
+	}
+	if _, err := New(Options{CABundle: "testdata/missing.pem"}); err == nil {
+		t.Errorf("New() accepted a missing CA bundle")
+	}
+}
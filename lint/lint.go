@@ -0,0 +1,147 @@
+package lint
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Rules checked when linting a food
+const (
+	// RuleParse fails when the food can not be evaluated
+	RuleParse = "parse"
+	// RuleDownload fails when a package can not be downloaded
+	RuleDownload = "download"
+	// RuleChecksum fails when a package does not match its sha256
+	RuleChecksum = "checksum"
+	// RuleMinSize fails when a package is suspiciously small
+	RuleMinSize = "min-size"
+	// RuleInstall fails when a package can not be unpacked or a resource is missing
+	RuleInstall = "install"
+	// RuleBinaryFormat fails when an executable is built for another os or arch
+	RuleBinaryFormat = "binary-format"
+	// RuleSmokeTest fails when the linux/amd64 binary does not print the version
+	RuleSmokeTest = "smoke-test"
+	// RuleMinPackages fails when the food has too few packages
+	RuleMinPackages = "min-packages"
+)
+
+// Severities of findings
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// Finding is a problem found by a lint rule, optionally for one package
+type Finding struct {
+	Rule     string `json:"rule"`
+	Severity string `json:"severity"`
+	OS       string `json:"os,omitempty"`
+	Arch     string `json:"arch,omitempty"`
+	Message  string `json:"message"`
+}
+
+// Package returns the os/arch of the package the finding is about, if any
+func (f Finding) Package() string {
+	if f.OS == "" && f.Arch == "" {
+		return ""
+	}
+	return f.OS + "/" + f.Arch
+}
+
+func (f Finding) String() string {
+	if pkg := f.Package(); pkg != "" {
+		return fmt.Sprintf("%s %s %s: %s", f.Severity, f.Rule, pkg, f.Message)
+	}
+	return fmt.Sprintf("%s %s: %s", f.Severity, f.Rule, f.Message)
+}
+
+// Report is the result of linting a food
+type Report struct {
+	Food     string    `json:"food"`
+	Version  string    `json:"version,omitempty"`
+	Rules    Rules     `json:"rules"`
+	Findings []Finding `json:"findings"`
+}
+
+// NewReport creates an empty report for the food checked with the rules
+func NewReport(food string, rules Rules) *Report {
+	return &Report{Food: food, Rules: rules, Findings: []Finding{}}
+}
+
+// Addf adds a finding of the rule with the severity of the rule set, unless
+// the rule is disabled. os and arch may be empty for findings about the
+// whole food.
+func (r *Report) Addf(rule, os, arch, format string, args ...interface{}) {
+	if !r.Rules.Enabled(rule) {
+		return
+	}
+	r.Findings = append(r.Findings, Finding{
+		Rule:     rule,
+		Severity: r.Rules.Severity(rule),
+		OS:       os,
+		Arch:     arch,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+// Has reports whether the report contains a finding of the rule
+func (r *Report) Has(rule string) bool {
+	for _, f := range r.Findings {
+		if f.Rule == rule {
+			return true
+		}
+	}
+	return false
+}
+
+// Errors returns the findings with error severity, ignoring the given rules
+func (r *Report) Errors(ignore ...string) []Finding {
+	errs := []Finding{}
+	for _, f := range r.Findings {
+		if f.Severity == SeverityError && !contains(ignore, f.Rule) {
+			errs = append(errs, f)
+		}
+	}
+	return errs
+}
+
+// Failed reports whether the report contains errors, ignoring the given rules
+func (r *Report) Failed(ignore ...string) bool {
+	return len(r.Errors(ignore...)) > 0
+}
+
+func (r *Report) String() string {
+	if len(r.Findings) == 0 {
+		return fmt.Sprintf("%s: ok", r.Food)
+	}
+	lines := []string{fmt.Sprintf("%s: %d findings", r.Food, len(r.Findings))}
+	for _, f := range r.Findings {
+		lines = append(lines, " - "+f.String())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Markdown renders the rules and findings for the pull request
+func (r *Report) Markdown() string {
+	rules := fmt.Sprintf("Rules: %s\n\n", r.Rules)
+	if len(r.Findings) == 0 {
+		return rules + "All lint checks passed.\n"
+	}
+	b := strings.Builder{}
+	b.WriteString(rules)
+	b.WriteString("| Rule | Severity | Package | Message |\n| --- | --- | --- | --- |\n")
+	for _, f := range r.Findings {
+		message := strings.Replace(strings.Replace(f.Message, "\n", " ", -1), "|", "\\|", -1)
+		fmt.Fprintf(&b, "| %s | %s | %s | %s |\n", f.Rule, f.Severity, f.Package(), message)
+	}
+	return b.String()
+}
+
+func contains(list []string, s string) bool {
+	for _, l := range list {
+		if l == s {
+			return true
+		}
+	}
+	return false
+}
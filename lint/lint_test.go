@@ -0,0 +1,147 @@
+package lint
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestReport(t *testing.T) {
+	r := NewReport("tool", DefaultRules())
+	if r.Failed() {
+		t.Errorf("Failed() of empty report = true")
+	}
+	if r.Markdown() != "Rules: min-packages 3; min-size 100 kB\n\nAll lint checks passed.\n" {
+		t.Errorf("Markdown() = %s", r.Markdown())
+	}
+
+	r.Addf(RuleChecksum, "linux", "amd64", "expected %s, downloaded %s", "abc", "def")
+	r.Addf(RuleMinPackages, "", "", "%d packages, expected at least %d", 2, 3)
+
+	tests := []struct {
+		name   string
+		ignore []string
+		want   int
+	}{
+		{name: "all", want: 2},
+		{name: "ignore min packages", ignore: []string{RuleMinPackages}, want: 1},
+		{name: "ignore both", ignore: []string{RuleMinPackages, RuleChecksum}, want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := len(r.Errors(tt.ignore...)); got != tt.want {
+				t.Errorf("Errors() = %d findings, want %d", got, tt.want)
+			}
+			if got := r.Failed(tt.ignore...); got != (tt.want > 0) {
+				t.Errorf("Failed() = %v, want %v", got, tt.want > 0)
+			}
+		})
+	}
+
+	if !r.Has(RuleChecksum) || r.Has(RuleSmokeTest) {
+		t.Errorf("Has() does not match the findings")
+	}
+	if !strings.Contains(r.Markdown(), "| checksum | error | linux/amd64 | expected abc, downloaded def |") {
+		t.Errorf("Markdown() = %s", r.Markdown())
+	}
+	if !strings.Contains(r.String(), "error min-packages: 2 packages, expected at least 3") {
+		t.Errorf("String() = %s", r.String())
+	}
+
+	b, err := json.Marshal(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"food":"tool","rules":{"min_packages":3,"min_size":100000},"findings":[{"rule":"checksum","severity":"error","os":"linux","arch":"amd64","message":"expected abc, downloaded def"},{"rule":"min-packages","severity":"error","message":"2 packages, expected at least 3"}]}`
+	if string(b) != want {
+		t.Errorf("json = %s, want %s", b, want)
+	}
+}
+
+func TestRules_Apply(t *testing.T) {
+	tests := []struct {
+		name    string
+		configs []*Config
+		want    string
+		wantErr bool
+	}{
+		{name: "defaults", configs: []*Config{nil}, want: "min-packages 3; min-size 100 kB"},
+		{
+			name:    "global",
+			configs: []*Config{{MinPackages: intPtr(2), MinSize: "10KB"}},
+			want:    "min-packages 2; min-size 10 kB",
+		},
+		{
+			name:    "app overrides global",
+			configs: []*Config{{MinPackages: intPtr(2), Disable: []string{RuleSmokeTest}}, {MinPackages: intPtr(1), Warn: []string{RuleMinSize}}},
+			want:    "min-packages 1; min-size 100 kB; disabled: smoke-test; warnings only: min-size",
+		},
+		{
+			name:    "disabled rules are merged",
+			configs: []*Config{{Disable: []string{RuleSmokeTest}}, {Disable: []string{RuleMinPackages, RuleSmokeTest}}},
+			want:    "min-packages 3; min-size 100 kB; disabled: min-packages, smoke-test",
+		},
+		{
+			name:    "thresholds can be set to 0",
+			configs: []*Config{{MinPackages: intPtr(0), MinSize: "0B"}},
+			want:    "min-packages 0; min-size 0 B",
+		},
+		{
+			name:    "unset thresholds are kept",
+			configs: []*Config{{MinPackages: intPtr(2)}, {Disable: []string{RuleSmokeTest}}},
+			want:    "min-packages 2; min-size 100 kB; disabled: smoke-test",
+		},
+		{
+			name:    "app enables a disabled rule",
+			configs: []*Config{{Disable: []string{RuleSmokeTest, RuleMinSize}}, {Enable: []string{RuleSmokeTest}}},
+			want:    "min-packages 3; min-size 100 kB; disabled: min-size",
+		},
+		{
+			name:    "app raises a warning to an error",
+			configs: []*Config{{Warn: []string{RuleMinPackages, RuleMinSize}}, {Error: []string{RuleMinPackages}}},
+			want:    "min-packages 3; min-size 100 kB; warnings only: min-size",
+		},
+		{name: "invalid size", configs: []*Config{{MinSize: "ten"}}, wantErr: true},
+		{name: "unknown rule", configs: []*Config{{Disable: []string{"bad-number"}}}, wantErr: true},
+		{name: "unknown enabled rule", configs: []*Config{{Enable: []string{"bad-number"}}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules := DefaultRules()
+			var err error
+			for _, c := range tt.configs {
+				rules, err = rules.Apply(c)
+				if err != nil {
+					break
+				}
+			}
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Apply() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && rules.String() != tt.want {
+				t.Errorf("Apply() = %s, want %s", rules, tt.want)
+			}
+		})
+	}
+}
+
+func TestReport_Rules(t *testing.T) {
+	rules, err := DefaultRules().Apply(&Config{Disable: []string{RuleMinPackages}, Warn: []string{RuleMinSize}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := NewReport("tool", rules)
+	r.Addf(RuleMinPackages, "", "", "2 packages")
+	r.Addf(RuleMinSize, "linux", "amd64", "to small")
+
+	if len(r.Findings) != 1 || r.Findings[0].Severity != SeverityWarning {
+		t.Errorf("Addf() findings = %v, want one warning", r.Findings)
+	}
+	if r.Failed() {
+		t.Errorf("Failed() with only warnings = true")
+	}
+}
+
+func intPtr(i int) *int {
+	return &i
+}
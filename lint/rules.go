@@ -0,0 +1,151 @@
+package lint
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/dustin/go-humanize"
+)
+
+// AllRules lists the id of every lint rule
+var AllRules = []string{
+	RuleParse,
+	RuleDownload,
+	RuleChecksum,
+	RuleMinSize,
+	RuleInstall,
+	RuleBinaryFormat,
+	RuleSmokeTest,
+	RuleMinPackages,
+}
+
+// Rules is the effective lint rule set of a food
+type Rules struct {
+	// MinPackages is the minimum number of packages of a food
+	MinPackages int `json:"min_packages"`
+	// MinSize is the minimum size in bytes of a package
+	MinSize int64 `json:"min_size"`
+	// Disabled rules are not checked
+	Disabled []string `json:"disabled,omitempty"`
+	// Warnings are rules reported with warning instead of error severity
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// DefaultRules returns the rules used when nothing is configured
+func DefaultRules() Rules {
+	return Rules{
+		MinPackages: 3,
+		MinSize:     100000,
+	}
+}
+
+// Config overrides the default rules, globally in the settings or per app
+type Config struct {
+	// MinPackages is only applied when set, so it can be set to 0
+	MinPackages *int   `yaml:"min_packages"`
+	MinSize     string `yaml:"min_size"`
+	// Disable lists rule ids that are not checked
+	Disable []string
+	// Warn lists rule ids that only produce warnings
+	Warn []string
+	// Enable lists rule ids that are checked again, when disabled before
+	Enable []string
+	// Error lists rule ids that produce errors again, when lowered to
+	// warnings before
+	Error []string
+}
+
+// Apply returns the rules overridden by the configured values of c. Rules
+// are disabled and lowered to warnings before they are enabled and raised
+// to errors again.
+func (r Rules) Apply(c *Config) (Rules, error) {
+	if c == nil {
+		return r, nil
+	}
+	if c.MinPackages != nil {
+		r.MinPackages = *c.MinPackages
+	}
+	if c.MinSize != "" {
+		size, err := humanize.ParseBytes(c.MinSize)
+		if err != nil {
+			return r, fmt.Errorf("invalid lint min_size '%s': %v", c.MinSize, err)
+		}
+		r.MinSize = int64(size)
+	}
+	for _, rules := range [][]string{c.Disable, c.Warn, c.Enable, c.Error} {
+		for _, rule := range rules {
+			if !contains(AllRules, rule) {
+				return r, fmt.Errorf("unknown lint rule '%s', expected one of %s", rule, strings.Join(AllRules, ", "))
+			}
+		}
+	}
+	r.Disabled = without(merge(r.Disabled, c.Disable), c.Enable)
+	r.Warnings = without(merge(r.Warnings, c.Warn), c.Error)
+	return r, nil
+}
+
+// Resolve returns the default rules overridden by the global configuration
+// and then by the configuration of the app, which may be nil
+func Resolve(global Config, app *Config) (Rules, error) {
+	rules, err := DefaultRules().Apply(&global)
+	if err != nil {
+		return rules, err
+	}
+	return rules.Apply(app)
+}
+
+// Enabled reports whether the rule is checked
+func (r Rules) Enabled(rule string) bool {
+	return !contains(r.Disabled, rule)
+}
+
+// Severity returns the severity of findings of the rule
+func (r Rules) Severity(rule string) string {
+	if contains(r.Warnings, rule) {
+		return SeverityWarning
+	}
+	return SeverityError
+}
+
+func (r Rules) String() string {
+	parts := []string{
+		fmt.Sprintf("min-packages %d", r.MinPackages),
+		fmt.Sprintf("min-size %s", humanize.Bytes(uint64(r.MinSize))),
+	}
+	if len(r.Disabled) > 0 {
+		parts = append(parts, "disabled: "+strings.Join(r.Disabled, ", "))
+	}
+	if len(r.Warnings) > 0 {
+		parts = append(parts, "warnings only: "+strings.Join(r.Warnings, ", "))
+	}
+	return strings.Join(parts, "; ")
+}
+
+// without returns a without the elements of b
+func without(a, b []string) []string {
+	rest := []string{}
+	for _, s := range a {
+		if !contains(b, s) {
+			rest = append(rest, s)
+		}
+	}
+	if len(rest) == 0 {
+		return nil
+	}
+	return rest
+}
+
+func merge(a, b []string) []string {
+	merged := append([]string{}, a...)
+	for _, s := range b {
+		if !contains(merged, s) {
+			merged = append(merged, s)
+		}
+	}
+	sort.Strings(merged)
+	if len(merged) == 0 {
+		return nil
+	}
+	return merged
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/urfave/cli"
+
+	"github.com/gofish-bot/gofish-bot/gofishgithub"
+	"github.com/gofish-bot/gofish-bot/lint"
+	"github.com/gofish-bot/gofish-bot/log"
+	"github.com/gofish-bot/gofish-bot/printer"
+	"github.com/gofish-bot/gofish-bot/publish"
+)
+
+func lintAllCommand(newGoFish func(ctx context.Context) *gofishgithub.GoFish, repoFlags *publish.Config, localRepo *string) cli.Command {
+	var dir string
+	var repo string
+	var parallel int
+	var output string
+
+	return cli.Command{
+		Name:      "lint-all",
+		Usage:     "Lint and install every food of a fish-food checkout",
+		ArgsUsage: "[food...]",
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:        "dir",
+				Usage:       "Local fish-food checkout, cloned into the workspace when not set",
+				Destination: &dir,
+			}, cli.StringFlag{
+				Name:        "repo",
+				Usage:       "Git url of fish-food (default: the repository foods are published to)",
+				Destination: &repo,
+			}, cli.IntFlag{
+				Name:        "parallel",
+				Usage:       "Number of foods linted at the same time",
+				Value:       4,
+				Destination: &parallel,
+			}, cli.StringFlag{
+				Name:        "output, o",
+				Usage:       "Format of the report, table or json",
+				Value:       "table",
+				Destination: &output,
+			},
+		},
+		Action: func(c *cli.Context) error {
+			ctx := context.Background()
+			goFish := newGoFish(ctx)
+			defer goFish.Workspace.Close()
+			defer goFish.Cache.Close()
+
+			settings := getSettings("config/settings.yaml")
+			rules, err := lint.Resolve(settings.Lint, nil)
+			if err != nil {
+				return err
+			}
+
+			if dir == "" {
+				if repo == "" {
+					repo = getPublishConfig(settings, *repoFlags, *localRepo).CloneURL()
+				}
+				dir = filepath.Join(goFish.Workspace.Dir, goFish.FoodRepo)
+				if err := cloneFishFood(repo, dir); err != nil {
+					return err
+				}
+			}
+
+			// Only the named foods are linted, from a copy of their Food files
+			if names := c.Args(); len(names) > 0 {
+				dir, err = selectFoods(dir, filepath.Join(goFish.Workspace.Dir, "lint-all"), names)
+				if err != nil {
+					return err
+				}
+			}
+
+			reports, err := goFish.LintDir(dir, rules, parallel)
+			if err != nil {
+				return err
+			}
+
+			if output == "json" {
+				enc := json.NewEncoder(os.Stdout)
+				enc.SetIndent("", "  ")
+				if err := enc.Encode(reports); err != nil {
+					return err
+				}
+			} else {
+				printer.LintReports(reports)
+			}
+
+			failed := 0
+			for _, report := range reports {
+				if report.Failed() {
+					failed++
+				}
+			}
+			if failed > 0 {
+				return fmt.Errorf("%d of %d foods failed linting", failed, len(reports))
+			}
+			log.G(ctx).Infof("All %d foods passed linting", len(reports))
+			return nil
+		},
+	}
+}
+
+// cloneFishFood makes a shallow clone of repo into dir, or updates an
+// existing clone
+func cloneFishFood(repo, dir string) error {
+	var cmd *exec.Cmd
+	if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+		log.L.Infof("Updating %s", dir)
+		cmd = exec.Command("git", "-C", dir, "pull", "--ff-only", "--depth", "1")
+	} else {
+		log.L.Infof("Cloning %s", repo)
+		cmd = exec.Command("git", "clone", "--depth", "1", repo, dir)
+	}
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%s: %v\n%s", cmd.Args, err, out)
+	}
+	return nil
+}
+
+// selectFoods copies the Food files of the named foods from the checkout in
+// dir into the Food directory of dest
+func selectFoods(dir, dest string, names []string) (string, error) {
+	if err := os.RemoveAll(dest); err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Join(dest, "Food"), 0755); err != nil {
+		return "", err
+	}
+	for _, name := range names {
+		file := filepath.Join("Food", name+".lua")
+		content, err := ioutil.ReadFile(filepath.Join(dir, file))
+		if err != nil {
+			return "", err
+		}
+		if err := ioutil.WriteFile(filepath.Join(dest, file), content, 0644); err != nil {
+			return "", err
+		}
+	}
+	return dest, nil
+}
@@ -14,34 +14,34 @@ import (
 	"github.com/gofish-bot/gofish-bot/log"
 
 	"github.com/gofish-bot/gofish-bot/models"
+	"github.com/gofish-bot/gofish-bot/publish"
 	"github.com/gofish-bot/gofish-bot/strategy/generic"
 	"github.com/gofish-bot/gofish-bot/strategy/github"
+	"github.com/gofish-bot/gofish-bot/workspace"
 
 	"github.com/go-yaml/yaml"
 	"github.com/urfave/cli"
 )
 
-const tmpDir = "/tmp/gofish-bot"
-
-func init() {
-	mkDir()
-}
-
-func mkDir() {
-	err := os.MkdirAll(tmpDir, os.ModePerm)
-	if err == nil || os.IsExist(err) {
-		return
-	} else {
-		panic(err)
-	}
-}
-
 func main() {
 
 	var clean bool
 	var apply bool
 	var verbose bool
+	var verify bool
+	var smokeTest bool
 	var target string
+	var cacheDir string
+	var cacheMaxSize string
+	var httpTimeout string
+	var httpRetries int
+	var proxy string
+	var caBundle string
+	var workspaceDir string
+	var localRepo string
+	var repoFlags publish.Config
+	var batchGroupBy string
+	var batchMaxSize int
 
 	app := cli.NewApp()
 	app.Version = "0.0.1"
@@ -55,6 +55,14 @@ func main() {
 			Name:        "target",
 			Usage:       "Target only one Food",
 			Destination: &target,
+		}, cli.BoolFlag{
+			Name:        "verify",
+			Usage:       "Download all assets and verify them against the published checksums",
+			Destination: &verify,
+		}, cli.BoolFlag{
+			Name:        "smoke-test",
+			Usage:       "Run the linux/amd64 binary when linting and check that it prints the new version",
+			Destination: &smokeTest,
 		}, cli.BoolFlag{
 			Name:        "verbose",
 			Usage:       "Full debug log",
@@ -63,51 +71,183 @@ func main() {
 			Name:        "clean, c",
 			Usage:       "Clear all cached packages",
 			Destination: &clean,
+		}, cli.StringFlag{
+			Name:        "cache-dir",
+			Usage:       "Directory of the download cache (default: /tmp/gofish-bot)",
+			EnvVar:      "GOFISH_BOT_CACHE_DIR",
+			Destination: &cacheDir,
+		}, cli.StringFlag{
+			Name:        "cache-max-size",
+			Usage:       "Maximum size of the download cache, eg. 200MB",
+			EnvVar:      "GOFISH_BOT_CACHE_MAX_SIZE",
+			Destination: &cacheMaxSize,
+		}, cli.StringFlag{
+			Name:        "http-timeout",
+			Usage:       "Timeout of each http request, eg. 5m",
+			EnvVar:      "GOFISH_BOT_HTTP_TIMEOUT",
+			Destination: &httpTimeout,
+		}, cli.IntFlag{
+			Name:        "http-retries",
+			Usage:       "Retries of http requests failing with transient errors (default: 3)",
+			EnvVar:      "GOFISH_BOT_HTTP_RETRIES",
+			Destination: &httpRetries,
+		}, cli.StringFlag{
+			Name:        "proxy",
+			Usage:       "Proxy url for all http requests, defaults to HTTPS_PROXY",
+			EnvVar:      "GOFISH_BOT_PROXY",
+			Destination: &proxy,
+		}, cli.StringFlag{
+			Name:        "ca-bundle",
+			Usage:       "Path to PEM encoded certificates to trust in addition to the system certificates",
+			EnvVar:      "GOFISH_BOT_CA_BUNDLE",
+			Destination: &caBundle,
+		}, cli.StringFlag{
+			Name:        "workspace",
+			Usage:       "Directory foods are rendered and linted in (default: a temporary directory)",
+			EnvVar:      "GOFISH_BOT_WORKSPACE",
+			Destination: &workspaceDir,
+		}, cli.StringFlag{
+			Name:        "local-repo",
+			Usage:       "Commit to branches of this local fish-food clone instead of opening pull requests on GitHub",
+			EnvVar:      "GOFISH_BOT_LOCAL_REPO",
+			Destination: &localRepo,
+		}, cli.StringFlag{
+			Name:        "food-org",
+			Usage:       "Owner of the fish-food repository pull requests are opened in (default: fishworks)",
+			EnvVar:      "GOFISH_BOT_FOOD_ORG",
+			Destination: &repoFlags.Org,
+		}, cli.StringFlag{
+			Name:        "food-repo",
+			Usage:       "Name of the fish-food repository (default: fish-food)",
+			EnvVar:      "GOFISH_BOT_FOOD_REPO",
+			Destination: &repoFlags.Repo,
+		}, cli.StringFlag{
+			Name:        "base-branch",
+			Usage:       "Branch pull requests are opened against (default: the default branch of the repository)",
+			EnvVar:      "GOFISH_BOT_BASE_BRANCH",
+			Destination: &repoFlags.Base,
+		}, cli.StringFlag{
+			Name:        "batch",
+			Usage:       "Group the updates into one pull request for all, per org or per label",
+			EnvVar:      "GOFISH_BOT_BATCH",
+			Destination: &batchGroupBy,
+		}, cli.IntFlag{
+			Name:        "batch-max-size",
+			Usage:       "Maximum number of foods in one batched pull request",
+			EnvVar:      "GOFISH_BOT_BATCH_MAX_SIZE",
+			Destination: &batchMaxSize,
 		},
 	}
 
-	app.Action = func(c *cli.Context) error {
-
+	// newLocalGoFish creates the download cache, http client and workspace
+	// shared by the commands, without access to the GitHub API
+	newLocalGoFish := func(ctx context.Context) *gofishgithub.GoFish {
 		if verbose {
 			log.L.Logger.SetLevel(logrus.DebugLevel)
 		}
 
+		settings := getSettings("config/settings.yaml")
+
+		downloadCache := getCache(settings, cacheDir, cacheMaxSize)
 		if clean {
-			clearDir(tmpDir)
+			log.L.Debugf("Cleaning: %s", downloadCache.Dir)
+			downloadCache.Clear()
 			clearDir(home.Cache())
 		}
 
-		githubOrg, err := envy.MustGet("GITHUB_ORG")
+		if workspaceDir == "" {
+			workspaceDir = settings.Workspace
+		}
+		foodWorkspace, err := workspace.New(workspaceDir)
+		if err != nil {
+			log.L.Fatalf("Error creating workspace: %v", err)
+		}
+		log.L.Debugf("Workspace: %s", foodWorkspace.Dir)
+
+		publishConfig := getPublishConfig(settings, repoFlags, localRepo)
+		return &gofishgithub.GoFish{
+			HTTPClient: getHTTPClient(settings, httpTimeout, httpRetries, proxy, caBundle),
+			Cache:      downloadCache,
+			Workspace:  foodWorkspace,
+			FoodRepo:   publishConfig.Repo,
+			FoodOrg:    publishConfig.Org,
+		}
+	}
+
+	// newGoFish adds the GitHub client, bot identity and publisher to
+	// newLocalGoFish. The GitHub credentials are only required when
+	// publishing to GitHub.
+	newGoFish := func(ctx context.Context) *gofishgithub.GoFish {
+		goFish := newLocalGoFish(ctx)
+		publishConfig := getPublishConfig(getSettings("config/settings.yaml"), repoFlags, localRepo)
+
+		get := envy.MustGet
+		if publishConfig.Type == publish.TypeGitHub {
+			goFish.Client = gofishgithub.CreateClient(ctx, goFish.HTTPClient)
+		} else {
+			get = func(key string) (string, error) { return envy.Get(key, ""), nil }
+			goFish.Client = gofishgithub.CreateReadClient(ctx, goFish.HTTPClient)
+		}
+
+		githubOrg, err := get("GITHUB_ORG")
 		if err != nil {
 			log.L.Fatalf("Error getting Github token: %v", err)
 		}
-		githubName, err := envy.MustGet("GITHUB_NAME")
+		githubName, err := get("GITHUB_NAME")
 		if err != nil {
 			log.L.Fatalf("Error getting Github token: %v", err)
 		}
-		githubEmail, err := envy.MustGet("GITHUB_EMAIL")
+		githubEmail, err := get("GITHUB_EMAIL")
 		if err != nil {
 			log.L.Fatalf("Error getting Github token: %v", err)
 		}
 
+		goFish.BotOrg = githubOrg
+		goFish.AuthorName = githubName
+		goFish.AuthorEmail = githubEmail
+
+		if publishConfig.Type == publish.TypeGitHub && publishConfig.Fork == "" {
+			publishConfig.Fork = githubOrg
+		}
+		goFish.Publisher, err = publish.New(publishConfig, goFish.Client, goFish.HTTPClient, publish.Author{Name: githubName, Email: githubEmail})
+		if err != nil {
+			log.L.Fatalf("Error creating publisher: %v", err)
+		}
+		return goFish
+	}
+
+	app.Commands = []cli.Command{
+		cacheCommand(&cacheDir, &cacheMaxSize),
+		auditCommand(newGoFish),
+		lintAllCommand(newLocalGoFish, &repoFlags, &localRepo),
+	}
+
+	app.Action = func(c *cli.Context) error {
 		ctx := context.Background()
+		goFish := newGoFish(ctx)
+		defer goFish.Workspace.Close()
+		defer goFish.Cache.Close()
 
-		client := gofishgithub.CreateClient(ctx)
-		goFish := &gofishgithub.GoFish{
-			Client:      client,
-			BotOrg:      githubOrg,
-			FoodRepo:    "fish-food",
-			FoodOrg:     "fishworks",
-			AuthorName:  githubName,
-			AuthorEmail: githubEmail,
+		settings := getSettings("config/settings.yaml")
+		if verify {
+			settings.Verify = true
+		}
+		if smokeTest {
+			settings.SmokeTest = true
+		}
+		if batchGroupBy != "" {
+			settings.Batch.GroupBy = batchGroupBy
+		}
+		if batchMaxSize != 0 {
+			settings.Batch.MaxSize = batchMaxSize
 		}
 
 		// Generic
-		gen := generic.Generic{GoFish: goFish}
+		gen := generic.Generic{GoFish: goFish, Settings: settings}
 		gen.UpdateApplications(ctx, getApps("config/generic.yaml", target), apply)
 
 		// Github
-		g := github.Github{GoFish: goFish}
+		g := github.Github{GoFish: goFish, Settings: settings}
 		g.UpdateApplications(ctx, getApps("config/apps.yaml", target), apply)
 		return nil
 	}
@@ -151,6 +291,42 @@ func getApps(path, target string) []models.DesiredApp {
 	return []models.DesiredApp{}
 }
 
+func getSettings(path string) models.Settings {
+
+	s := models.Settings{}
+
+	yamlFile, err := ioutil.ReadFile(path)
+	if err != nil {
+		log.L.Printf("yamlFile.Get err   #%v ", err)
+		return s
+	}
+	err = yaml.Unmarshal(yamlFile, &s)
+	if err != nil {
+		log.L.Fatalf("Unmarshal: %v", err)
+	}
+	return s
+}
+
+// getPublishConfig returns the publish settings overridden by the flags,
+// publishing to the local clone when set
+func getPublishConfig(settings models.Settings, flags publish.Config, localRepo string) publish.Config {
+	c := settings.Publish
+	if localRepo != "" {
+		c.Type = publish.TypeGit
+		c.URL = localRepo
+	}
+	if flags.Org != "" {
+		c.Org = flags.Org
+	}
+	if flags.Repo != "" {
+		c.Repo = flags.Repo
+	}
+	if flags.Base != "" {
+		c.Base = flags.Base
+	}
+	return c.WithDefaults()
+}
+
 func clearDir(dir string) error {
 	log.L.Debugf("Cleaning: %s", dir)
 	names, err := ioutil.ReadDir(dir)
@@ -1,21 +1,125 @@
 package models
 
+import (
+	"github.com/gofish-bot/gofish-bot/lint"
+	"github.com/gofish-bot/gofish-bot/publish"
+)
+
 type DesiredApp struct {
-	Repo string
-	Org  string
-	Arch string
-	Name string
-	Path string
+	Repo   string
+	Org    string
+	Arch   string
+	Name   string
+	Path   string
+	URL    string
+	Prefer []string
+	Avoid  []string
+	Verify *Verification
+	// SmokeArgs are the arguments the linux/amd64 binary is run with when
+	// smoke testing, default --version
+	SmokeArgs []string `yaml:"smoke_args"`
+	// Lint overrides the global lint rules for this app
+	Lint *lint.Config
+	// Label groups apps into one pull request when batching by label
+	Label string
+}
+
+// Verification targets
+const (
+	VerifyChecksums = "checksums"
+	VerifyAssets    = "assets"
+)
+
+// Verification describes how the signatures of release artifacts are verified
+type Verification struct {
+	// Method is one of cosign, gpg or minisign
+	Method string
+	// Key is the path to the trusted public key
+	Key string
+	// Target is either checksums (default), verifying the signature of the
+	// checksums file, or assets, verifying the signature of every asset
+	Target string
 }
 
+// Settings are the global settings of the bot, shared by all strategies
+type Settings struct {
+	// Prefer lists asset name fragments in order of preference, used when
+	// multiple assets exists for the same os/arch
+	Prefer []string
+	// Avoid lists asset name fragments that are only selected as a last resort
+	Avoid []string
+	// Verify downloads every asset and compares it to the published checksums
+	Verify bool
+	// SmokeTest runs the linux/amd64 binary when linting and checks that it
+	// prints the new version
+	SmokeTest bool `yaml:"smoke_test"`
+	// Lint overrides the default lint rules for all apps
+	Lint lint.Config
+	// CacheDir is the directory of the download cache
+	CacheDir string `yaml:"cache_dir"`
+	// CacheMaxSize is the maximum size of the download cache, eg. 200MB
+	CacheMaxSize string `yaml:"cache_max_size"`
+	// Workspace is the directory foods are rendered and linted in, a
+	// temporary directory is used when empty
+	Workspace string
+	// HTTP configures the client used for the GitHub API and all downloads
+	HTTP HTTPSettings
+	// Publish selects the forge and repository foods are published to
+	Publish publish.Config
+	// Batch groups the updates of several foods into one pull request
+	Batch BatchSettings
+}
+
+// BatchSettings groups food updates into shared pull requests
+type BatchSettings struct {
+	// GroupBy is all, org or label. Every food gets its own pull request
+	// when empty.
+	GroupBy string `yaml:"group_by"`
+	// MaxSize is the maximum number of foods in one pull request, 0 is
+	// unlimited
+	MaxSize int `yaml:"max_size"`
+}
+
+// HTTPSettings configures the HTTP client of the bot
+type HTTPSettings struct {
+	// Timeout of each request, eg. 5m
+	Timeout string
+	// Retries of requests failing with transient errors
+	Retries int
+	// Proxy url, defaults to the HTTP_PROXY and HTTPS_PROXY environment variables
+	Proxy string
+	// CABundle is the path to additional trusted certificates
+	CABundle string `yaml:"ca_bundle"`
+	// UserAgent sent with every request
+	UserAgent string `yaml:"user_agent"`
+}
+
+// Sources of the sha256 of an asset
+const (
+	ChecksumVerified  = "published and verified"
+	ChecksumPublished = "published only"
+	ChecksumComputed  = "computed only"
+)
+
 type Asset struct {
 	Arch        string
 	Os          string
 	AssertName  string
 	InstallPath string
 	Path        string
+	URL         string
 	Sha256      string
-	Executable  bool
+	// ChecksumSource tells if the sha256 was published upstream and verified
+	ChecksumSource string
+	Executable     bool
+}
+
+// ReleaseNote is the description of a release of an application
+type ReleaseNote struct {
+	Name        string
+	Version     string
+	Link        string
+	Description string
 }
 
 type Application struct {
@@ -29,8 +133,21 @@ type Application struct {
 	CurrentVersion     string
 	Version            string
 	Arch               string
+	URL                string
+	Prefer             []string
+	Avoid              []string
+	Verification       *Verification
+	SmokeTest          bool
+	SmokeArgs          []string
+	LintRules          lint.Rules
+	Label              string
 	Description        string
 	Licence            string
 	Homepage           string
 	Assets             []Asset
+	// ReleaseNotes of the releases of the application, the changelog since
+	// the current version is aggregated from them
+	ReleaseNotes []ReleaseNote
+	// LintReport is the result of linting the rendered food
+	LintReport *lint.Report
 }
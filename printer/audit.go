@@ -0,0 +1,30 @@
+package printer
+
+import (
+	"fmt"
+
+	"github.com/gofish-bot/gofish-bot/audit"
+
+	"github.com/fatih/color"
+	"github.com/rodaine/table"
+)
+
+func AuditResults(results []audit.Result) {
+
+	headerFmt := color.New(color.FgGreen, color.Underline).SprintfFunc()
+	columnFmt := color.New(color.FgYellow).SprintfFunc()
+
+	tbl := table.New("Food", "Version", "Package", "Status", "Details")
+	tbl.WithHeaderFormatter(headerFmt).WithFirstColumnFormatter(columnFmt)
+
+	for _, result := range results {
+		details := result.Error
+		if result.Status == audit.StatusMismatch {
+			details = fmt.Sprintf("recorded %s, downloaded %s", result.Expected, result.Actual)
+		}
+		tbl.AddRow(result.Food, result.Version, result.OS+"/"+result.Arch, result.Status, details)
+	}
+
+	tbl.Print()
+	color.New(color.FgGreen).Printf("\n%d packages, %d problems\n", len(results), len(audit.Problems(results)))
+}
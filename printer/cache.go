@@ -0,0 +1,31 @@
+package printer
+
+import (
+	"github.com/dustin/go-humanize"
+	"github.com/gofish-bot/gofish-bot/cache"
+
+	"github.com/fatih/color"
+	"github.com/rodaine/table"
+)
+
+func CacheEntries(entries []*cache.Entry) {
+
+	headerFmt := color.New(color.FgGreen, color.Underline).SprintfFunc()
+	columnFmt := color.New(color.FgYellow).SprintfFunc()
+
+	tbl := table.New("Url", "Sha256", "Size", "LastUsed")
+	tbl.WithHeaderFormatter(headerFmt).WithFirstColumnFormatter(columnFmt)
+
+	var total int64
+	seen := map[string]bool{}
+	for _, entry := range entries {
+		if !seen[entry.Path] {
+			total += entry.Size
+			seen[entry.Path] = true
+		}
+		tbl.AddRow(entry.URL, entry.SHA256[:12], humanize.Bytes(uint64(entry.Size)), humanize.Time(entry.LastUsed))
+	}
+
+	tbl.Print()
+	color.New(color.FgGreen).Printf("\n%d downloads, %s\n", len(entries), humanize.Bytes(uint64(total)))
+}
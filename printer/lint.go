@@ -0,0 +1,64 @@
+package printer
+
+import (
+	"strings"
+
+	"github.com/gofish-bot/gofish-bot/lint"
+
+	"github.com/fatih/color"
+	"github.com/rodaine/table"
+)
+
+func LintReport(report *lint.Report) {
+
+	color.New(color.FgGreen).Printf("\nRules: %s\n", report.Rules)
+	if len(report.Findings) == 0 {
+		color.New(color.FgGreen).Printf("%s %s: all lint checks passed\n", report.Food, report.Version)
+		return
+	}
+
+	headerFmt := color.New(color.FgGreen, color.Underline).SprintfFunc()
+	columnFmt := color.New(color.FgYellow).SprintfFunc()
+
+	tbl := table.New("Rule", "Severity", "Package", "Message")
+	tbl.WithHeaderFormatter(headerFmt).WithFirstColumnFormatter(columnFmt)
+
+	for _, f := range report.Findings {
+		tbl.AddRow(f.Rule, f.Severity, f.Package(), f.Message)
+	}
+
+	tbl.Print()
+}
+
+func LintReports(reports []*lint.Report) {
+
+	headerFmt := color.New(color.FgGreen, color.Underline).SprintfFunc()
+	columnFmt := color.New(color.FgYellow).SprintfFunc()
+
+	tbl := table.New("Food", "Version", "Status", "Errors", "Warnings", "Rules")
+	tbl.WithHeaderFormatter(headerFmt).WithFirstColumnFormatter(columnFmt)
+
+	for _, report := range reports {
+		status := "ok"
+		if report.Failed() {
+			status = "failed"
+		}
+		errors := len(report.Errors())
+		tbl.AddRow(report.Food, report.Version, status, errors, len(report.Findings)-errors, failedRules(report))
+	}
+
+	tbl.Print()
+}
+
+// failedRules lists the rules with findings, in the order they were found
+func failedRules(report *lint.Report) string {
+	rules := []string{}
+	seen := map[string]bool{}
+	for _, f := range report.Findings {
+		if !seen[f.Rule] {
+			seen[f.Rule] = true
+			rules = append(rules, f.Rule)
+		}
+	}
+	return strings.Join(rules, ", ")
+}
@@ -0,0 +1,101 @@
+package publish
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	ghApi "github.com/google/go-github/v32/github"
+)
+
+// Publisher types
+const (
+	TypeGitHub = "github"
+	TypeGitea  = "gitea"
+	TypeGitLab = "gitlab"
+	TypeGit    = "git"
+)
+
+// Config selects the publisher and the repository foods are published to
+type Config struct {
+	// Type is github (default), gitea, gitlab or git
+	Type string
+	// URL is the address of the Gitea or GitLab server, or the directory of
+	// the local clone for git
+	URL string
+	// TokenEnv names the environment variable holding the API token of the
+	// Gitea or GitLab server
+	TokenEnv string `yaml:"token_env"`
+	// Org and Repo of the repository pull requests are opened in, default
+	// fishworks/fish-food
+	Org  string
+	Repo string
+	// Fork is the owner of the fork branches are pushed to. On GitHub it
+	// defaults to the bot, elsewhere branches are pushed to the repository.
+	Fork string
+	// Base is the branch pull requests are opened against, by default the
+	// default branch of the repository
+	Base string
+}
+
+// WithDefaults returns the config with defaults for all unset fields
+func (c Config) WithDefaults() Config {
+	if c.Type == "" {
+		c.Type = TypeGitHub
+	}
+	if c.Org == "" {
+		c.Org = "fishworks"
+	}
+	if c.Repo == "" {
+		c.Repo = "fish-food"
+	}
+	return c
+}
+
+// CloneURL returns the url the repository is cloned from
+func (c Config) CloneURL() string {
+	c = c.WithDefaults()
+	switch c.Type {
+	case TypeGit:
+		return c.URL
+	case TypeGitea, TypeGitLab:
+		return fmt.Sprintf("%s/%s/%s.git", strings.TrimSuffix(c.URL, "/"), c.Org, c.Repo)
+	}
+	return fmt.Sprintf("https://github.com/%s/%s.git", c.Org, c.Repo)
+}
+
+// New creates the publisher selected by the config. The GitHub client is
+// only used by the github publisher, httpClient by gitea and gitlab.
+func New(c Config, githubClient *ghApi.Client, httpClient *http.Client, author Author) (Publisher, error) {
+	c = c.WithDefaults()
+
+	var token string
+	if c.Type == TypeGitea || c.Type == TypeGitLab {
+		if c.URL == "" {
+			return nil, fmt.Errorf("publish url of %s is not set", c.Type)
+		}
+		if c.TokenEnv == "" {
+			return nil, fmt.Errorf("publish token_env of %s is not set", c.Type)
+		}
+		token = os.Getenv(c.TokenEnv)
+		if token == "" {
+			return nil, fmt.Errorf("publish token %s is not set", c.TokenEnv)
+		}
+	}
+
+	switch c.Type {
+	case TypeGitHub:
+		return &GitHub{Client: githubClient, Owner: c.Fork, Upstream: c.Org, Repo: c.Repo, Base: c.Base, Author: author}, nil
+	case TypeGitea:
+		return &Gitea{URL: c.URL, Token: token, Owner: c.Org, Repo: c.Repo, Fork: c.Fork, Base: c.Base, Author: author, Client: httpClient}, nil
+	case TypeGitLab:
+		return &GitLab{URL: c.URL, Token: token, Owner: c.Org, Repo: c.Repo, Fork: c.Fork, Base: c.Base, Author: author, Client: httpClient}, nil
+	case TypeGit:
+		if c.URL == "" {
+			return nil, fmt.Errorf("publish url of git is not set")
+		}
+		return &Git{Dir: c.URL, Base: c.Base, Author: author}, nil
+	}
+	return nil, fmt.Errorf("unknown publish type '%s', expected one of github, gitea, gitlab or git", c.Type)
+}
@@ -0,0 +1,56 @@
+package publish
+
+import (
+	"fmt"
+	"os"
+	"testing"
+)
+
+func TestNew(t *testing.T) {
+	os.Setenv("GOFISH_BOT_TEST_TOKEN", "secret")
+	defer os.Unsetenv("GOFISH_BOT_TEST_TOKEN")
+
+	tests := []struct {
+		name     string
+		config   Config
+		wantType string
+		wantErr  bool
+	}{
+		{"default", Config{}, "*publish.GitHub", false},
+		{"gitea", Config{Type: "gitea", URL: "https://gitea.example.com", TokenEnv: "GOFISH_BOT_TEST_TOKEN"}, "*publish.Gitea", false},
+		{"gitlab", Config{Type: "gitlab", URL: "https://gitlab.example.com", TokenEnv: "GOFISH_BOT_TEST_TOKEN"}, "*publish.GitLab", false},
+		{"git", Config{Type: "git", URL: "/tmp/fish-food"}, "*publish.Git", false},
+		{"gitea without url", Config{Type: "gitea", TokenEnv: "GOFISH_BOT_TEST_TOKEN"}, "", true},
+		{"gitlab without token", Config{Type: "gitlab", URL: "https://gitlab.example.com"}, "", true},
+		{"gitlab with missing token", Config{Type: "gitlab", URL: "https://gitlab.example.com", TokenEnv: "GOFISH_BOT_MISSING_TOKEN"}, "", true},
+		{"git without url", Config{Type: "git"}, "", true},
+		{"unknown", Config{Type: "bitbucket"}, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := New(tt.config, nil, nil, Author{})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("New() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && fmt.Sprintf("%T", got) != tt.wantType {
+				t.Errorf("New() = %T, want %s", got, tt.wantType)
+			}
+		})
+	}
+}
+
+func TestConfig_CloneURL(t *testing.T) {
+	tests := []struct {
+		config Config
+		want   string
+	}{
+		{Config{}, "https://github.com/fishworks/fish-food.git"},
+		{Config{Type: "gitea", URL: "https://gitea.example.com/", Org: "platform"}, "https://gitea.example.com/platform/fish-food.git"},
+		{Config{Type: "git", URL: "/tmp/fish-food"}, "/tmp/fish-food"},
+	}
+	for _, tt := range tests {
+		if got := tt.config.CloneURL(); got != tt.want {
+			t.Errorf("CloneURL() = %s, want %s", got, tt.want)
+		}
+	}
+}
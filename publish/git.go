@@ -0,0 +1,170 @@
+package publish
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/gofish-bot/gofish-bot/log"
+)
+
+// Git publishes to a local clone of fish-food. Commits are written with git
+// plumbing commands, so neither the checked out branch nor the working tree
+// of the clone are touched. Pull requests are written as markdown files into
+// the pull-requests directory of the git directory.
+type Git struct {
+	// Dir is the directory of the clone
+	Dir string
+	// Base is the branch new branches are created from, by default the
+	// checked out branch of the clone
+	Base string
+	// Author of the commits, by default the identity configured in the clone
+	Author Author
+}
+
+// BaseBranch returns the base branch, the checked out branch of the clone when
+// not set
+func (g *Git) BaseBranch(ctx context.Context) (string, error) {
+	if g.Base == "" {
+		branch, err := g.git(nil, nil, "symbolic-ref", "--short", "HEAD")
+		if err != nil {
+			return "", err
+		}
+		g.Base = branch
+	}
+	return g.Base, nil
+}
+
+// CreateBranch creates the branch at the head of the base branch
+func (g *Git) CreateBranch(ctx context.Context, branch string) error {
+	log.G(ctx).Debugf("Creating new Branch %s", branch)
+	base, err := g.BaseBranch(ctx)
+	if err != nil {
+		return err
+	}
+	_, err = g.git(nil, nil, "branch", "--no-track", branch, base)
+	return err
+}
+
+// WriteFile commits the content to the file at path on top of the branch
+func (g *Git) WriteFile(ctx context.Context, branch, path string, content []byte, message string) error {
+	log.G(ctx).Debugf("Writing %s to %s", path, branch)
+	ref := "refs/heads/" + branch
+
+	parent, err := g.git(nil, nil, "rev-parse", "--verify", ref)
+	if err != nil {
+		return err
+	}
+	blob, err := g.git(nil, content, "hash-object", "-w", "--stdin")
+	if err != nil {
+		return err
+	}
+
+	// The tree is built in a temporary index, leaving the index of the clone alone
+	index, err := ioutil.TempFile("", "gofish-bot-index-")
+	if err != nil {
+		return err
+	}
+	index.Close()
+	defer os.Remove(index.Name())
+	env := []string{"GIT_INDEX_FILE=" + index.Name()}
+
+	if _, err := g.git(env, nil, "read-tree", parent); err != nil {
+		return err
+	}
+	if _, err := g.git(env, nil, "update-index", "--add", "--cacheinfo", "100644,"+blob+","+filepath.ToSlash(path)); err != nil {
+		return err
+	}
+	tree, err := g.git(env, nil, "write-tree")
+	if err != nil {
+		return err
+	}
+
+	if g.Author.Name != "" {
+		env = append(env, "GIT_AUTHOR_NAME="+g.Author.Name, "GIT_COMMITTER_NAME="+g.Author.Name)
+	}
+	if g.Author.Email != "" {
+		env = append(env, "GIT_AUTHOR_EMAIL="+g.Author.Email, "GIT_COMMITTER_EMAIL="+g.Author.Email)
+	}
+	commit, err := g.git(env, nil, "commit-tree", tree, "-p", parent, "-m", message)
+	if err != nil {
+		return err
+	}
+	_, err = g.git(nil, nil, "update-ref", ref, commit, parent)
+	return err
+}
+
+// OpenPullRequest writes the pull request to a markdown file named after the
+// branch, and returns its path
+func (g *Git) OpenPullRequest(ctx context.Context, pr PullRequest) (string, error) {
+	base, err := g.BaseBranch(ctx)
+	if err != nil {
+		return "", err
+	}
+	gitDir, err := g.git(nil, nil, "rev-parse", "--absolute-git-dir")
+	if err != nil {
+		return "", err
+	}
+	file := filepath.Join(gitDir, "pull-requests", pr.Branch+".md")
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return "", err
+	}
+
+	content := fmt.Sprintf("# %s\n\nMerge %s into %s\n\n%s\n", pr.Title, pr.Branch, base, pr.Body)
+	if err := ioutil.WriteFile(file, []byte(content), 0644); err != nil {
+		return "", err
+	}
+	return file, nil
+}
+
+// ReadFile reads the file at path in ref of the clone
+func (g *Git) ReadFile(ctx context.Context, ref, path string) ([]byte, error) {
+	return g.run(nil, nil, "show", ref+":"+filepath.ToSlash(path))
+}
+
+// ListFiles lists the files in the directory dir in ref of the clone
+func (g *Git) ListFiles(ctx context.Context, ref, dir string) ([]string, error) {
+	out, err := g.run(nil, nil, "ls-tree", "-z", ref+":"+filepath.ToSlash(dir))
+	if err != nil {
+		return nil, err
+	}
+	names := []string{}
+	for _, entry := range strings.Split(string(out), "\x00") {
+		// Entries are "<mode> <type> <object>\t<name>"
+		i := strings.IndexByte(entry, '\t')
+		if i < 0 {
+			continue
+		}
+		if fields := strings.Fields(entry[:i]); len(fields) < 2 || fields[1] != "blob" {
+			continue
+		}
+		names = append(names, entry[i+1:])
+	}
+	return names, nil
+}
+
+// git runs git in the clone and returns its trimmed output
+func (g *Git) git(env []string, stdin []byte, args ...string) (string, error) {
+	out, err := g.run(env, stdin, args...)
+	return strings.TrimSpace(string(out)), err
+}
+
+func (g *Git) run(env []string, stdin []byte, args ...string) ([]byte, error) {
+	cmd := exec.Command("git", append([]string{"-C", g.Dir}, args...)...)
+	cmd.Env = append(os.Environ(), env...)
+	if stdin != nil {
+		cmd.Stdin = bytes.NewReader(stdin)
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("git %s: %v: %s", strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+	return out, nil
+}
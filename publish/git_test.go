@@ -0,0 +1,103 @@
+package publish
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestGit(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gofish-bot-publish")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	run := func(args ...string) string {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+		cmd.Env = append(os.Environ(),
+			"GIT_AUTHOR_NAME=test", "GIT_AUTHOR_EMAIL=test@example.com",
+			"GIT_COMMITTER_NAME=test", "GIT_COMMITTER_EMAIL=test@example.com",
+		)
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+		return strings.TrimSpace(string(out))
+	}
+
+	run("init", "-q", "-b", "main")
+	if err := os.MkdirAll(filepath.Join(dir, "Food"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "Food", "tool.lua"), []byte("old"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	run("add", "-A")
+	run("commit", "-q", "-m", "init")
+
+	ctx := context.Background()
+	g := &Git{Dir: dir, Author: Author{Name: "gofish-bot", Email: "bot@example.com"}}
+
+	if err := g.CreateBranch(ctx, "tool-1.0.0"); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.CreateBranch(ctx, "tool-1.0.0"); err == nil {
+		t.Errorf("CreateBranch() of an existing branch did not fail")
+	}
+	if err := g.WriteFile(ctx, "tool-1.0.0", "Food/tool.lua", []byte("new"), "tool 1.0.0"); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.WriteFile(ctx, "tool-1.0.0", "Food/other.lua", []byte("other"), "other 2.0.0"); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.WriteFile(ctx, "missing", "Food/tool.lua", []byte("new"), "tool 1.0.0"); err == nil {
+		t.Errorf("WriteFile() to a missing branch did not fail")
+	}
+
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"show", "tool-1.0.0:Food/tool.lua"}, "new"},
+		{[]string{"show", "tool-1.0.0:Food/other.lua"}, "other"},
+		{[]string{"log", "--format=%s", "tool-1.0.0"}, "other 2.0.0\ntool 1.0.0\ninit"},
+		{[]string{"log", "-1", "--format=%an <%ae>", "tool-1.0.0"}, "gofish-bot <bot@example.com>"},
+		// The checked out branch and working tree are left alone
+		{[]string{"rev-parse", "--abbrev-ref", "HEAD"}, "main"},
+		{[]string{"show", "main:Food/tool.lua"}, "old"},
+		{[]string{"status", "--porcelain"}, ""},
+	}
+	for _, tt := range tests {
+		if got := run(tt.args...); got != tt.want {
+			t.Errorf("git %v = %q, want %q", tt.args, got, tt.want)
+		}
+	}
+
+	files, err := g.ListFiles(ctx, "tool-1.0.0", "Food")
+	if err != nil || !reflect.DeepEqual(files, []string{"other.lua", "tool.lua"}) {
+		t.Errorf("ListFiles() = %v, %v, want [other.lua tool.lua]", files, err)
+	}
+	if _, err := g.ListFiles(ctx, "tool-1.0.0", "missing"); err == nil {
+		t.Errorf("ListFiles() of a missing directory did not fail")
+	}
+
+	link, err := g.OpenPullRequest(ctx, PullRequest{Branch: "tool-1.0.0", Title: "tool 1.0.0", Body: "Updating package tool"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, err := ioutil.ReadFile(link)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "# tool 1.0.0\n\nMerge tool-1.0.0 into main\n\nUpdating package tool\n"
+	if string(content) != want {
+		t.Errorf("OpenPullRequest() wrote %q, want %q", content, want)
+	}
+}
@@ -0,0 +1,186 @@
+package publish
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gofish-bot/gofish-bot/log"
+)
+
+// Gitea publishes to a repository on a Gitea server through its REST API
+type Gitea struct {
+	// URL of the Gitea server, eg. https://gitea.example.com
+	URL   string
+	Token string
+	// Owner and Repo of the repository pull requests are opened in
+	Owner string
+	Repo  string
+	// Fork is the owner of the fork branches are pushed to. Branches are
+	// pushed to the repository itself when empty.
+	Fork string
+	// Base is the branch pull requests are opened against, by default the
+	// default branch of the repository
+	Base   string
+	Author Author
+	Client *http.Client
+}
+
+type giteaIdentity struct {
+	Name  string `json:"name"`
+	Email string `json:"email"`
+}
+
+type giteaFile struct {
+	SHA     string `json:"sha"`
+	Content string `json:"content"`
+}
+
+// BaseBranch returns the base branch, discovering the default branch of the
+// repository when not set
+func (g *Gitea) BaseBranch(ctx context.Context) (string, error) {
+	if g.Base == "" {
+		repo := struct {
+			DefaultBranch string `json:"default_branch"`
+		}{}
+		err := g.api().do(ctx, http.MethodGet, fmt.Sprintf("/repos/%s/%s", url.PathEscape(g.Owner), url.PathEscape(g.Repo)), nil, &repo)
+		if err != nil {
+			return "", fmt.Errorf("Error getting default branch of %s/%s: %v", g.Owner, g.Repo, err)
+		}
+		g.Base = repo.DefaultBranch
+	}
+	return g.Base, nil
+}
+
+// head is the owner branches are pushed to
+func (g *Gitea) head() string {
+	if g.Fork == "" {
+		return g.Owner
+	}
+	return g.Fork
+}
+
+func (g *Gitea) api() *restClient {
+	return &restClient{
+		client:  g.Client,
+		baseURL: g.URL + "/api/v1",
+		header:  http.Header{"Authorization": {"token " + g.Token}},
+	}
+}
+
+func (g *Gitea) contentsPath(owner, path string) string {
+	return fmt.Sprintf("/repos/%s/%s/contents/%s", url.PathEscape(owner), url.PathEscape(g.Repo), path)
+}
+
+// CreateBranch creates the branch in the fork, or the repository. The base
+// branch of the fork is synced with the repository first.
+func (g *Gitea) CreateBranch(ctx context.Context, branch string) error {
+	log.G(ctx).Debugf("Creating new Branch %s", branch)
+	base, err := g.BaseBranch(ctx)
+	if err != nil {
+		return err
+	}
+	if g.head() != g.Owner {
+		if err := g.syncFork(ctx, base); err != nil {
+			return fmt.Errorf("Error syncing %s/%s with %s: %v", g.Fork, g.Repo, g.Owner, err)
+		}
+	}
+	in := map[string]string{"new_branch_name": branch, "old_branch_name": base}
+	return g.api().do(ctx, http.MethodPost, fmt.Sprintf("/repos/%s/%s/branches", url.PathEscape(g.head()), url.PathEscape(g.Repo)), in, nil)
+}
+
+// syncFork fast-forwards or merges the base branch of the fork with the
+// repository
+func (g *Gitea) syncFork(ctx context.Context, base string) error {
+	res := struct {
+		MergeType string `json:"merge_type"`
+	}{}
+	err := g.api().do(ctx, http.MethodPost, fmt.Sprintf("/repos/%s/%s/merge-upstream", url.PathEscape(g.Fork), url.PathEscape(g.Repo)), map[string]string{"branch": base}, &res)
+	if err != nil {
+		return err
+	}
+	log.G(ctx).Debugf("Synced %s/%s: %s", g.Fork, g.Repo, res.MergeType)
+	return nil
+}
+
+// WriteFile creates or updates the file in the branch
+func (g *Gitea) WriteFile(ctx context.Context, branch, path string, content []byte, message string) error {
+	author := giteaIdentity{Name: g.Author.Name, Email: g.Author.Email}
+	in := map[string]interface{}{
+		"branch":    branch,
+		"content":   base64.StdEncoding.EncodeToString(content),
+		"message":   message,
+		"author":    author,
+		"committer": author,
+	}
+
+	existing := &giteaFile{}
+	err := g.api().do(ctx, http.MethodGet, g.contentsPath(g.head(), path)+"?ref="+url.QueryEscape(branch), nil, existing)
+	switch {
+	case err == nil:
+		log.G(ctx).Debugf("Updating %s", path)
+		in["sha"] = existing.SHA
+		return g.api().do(ctx, http.MethodPut, g.contentsPath(g.head(), path), in, nil)
+	case isNotFound(err):
+		log.G(ctx).Debugf("Creating %s", path)
+		return g.api().do(ctx, http.MethodPost, g.contentsPath(g.head(), path), in, nil)
+	default:
+		return err
+	}
+}
+
+// OpenPullRequest opens a pull request into the base branch of the repository
+func (g *Gitea) OpenPullRequest(ctx context.Context, pr PullRequest) (string, error) {
+	log.G(ctx).Debugf("Sending pull request")
+	base, err := g.BaseBranch(ctx)
+	if err != nil {
+		return "", err
+	}
+	head := pr.Branch
+	if g.Fork != "" {
+		head = g.Fork + ":" + pr.Branch
+	}
+	in := map[string]string{
+		"head":  head,
+		"base":  base,
+		"title": pr.Title,
+		"body":  pr.Body,
+	}
+	out := struct {
+		HTMLURL string `json:"html_url"`
+	}{}
+	err = g.api().do(ctx, http.MethodPost, fmt.Sprintf("/repos/%s/%s/pulls", url.PathEscape(g.Owner), url.PathEscape(g.Repo)), in, &out)
+	return out.HTMLURL, err
+}
+
+// ReadFile reads the file at path in ref of the repository
+func (g *Gitea) ReadFile(ctx context.Context, ref, path string) ([]byte, error) {
+	file := &giteaFile{}
+	err := g.api().do(ctx, http.MethodGet, g.contentsPath(g.Owner, path)+"?ref="+url.QueryEscape(ref), nil, file)
+	if err != nil {
+		return nil, err
+	}
+	return base64.StdEncoding.DecodeString(strings.ReplaceAll(file.Content, "\n", ""))
+}
+
+// ListFiles lists the files in the directory dir in ref of the repository
+func (g *Gitea) ListFiles(ctx context.Context, ref, dir string) ([]string, error) {
+	entries := []struct {
+		Name string `json:"name"`
+		Type string `json:"type"`
+	}{}
+	err := g.api().do(ctx, http.MethodGet, g.contentsPath(g.Owner, dir)+"?ref="+url.QueryEscape(ref), nil, &entries)
+	if err != nil {
+		return nil, err
+	}
+	names := []string{}
+	for _, entry := range entries {
+		if entry.Type == "file" {
+			names = append(names, entry.Name)
+		}
+	}
+	return names, nil
+}
@@ -0,0 +1,147 @@
+package publish
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// fakeGitea serves the part of the Gitea API used by the publisher, with the
+// files of each branch of the repository and the fork of the bot kept in
+// memory
+type fakeGitea struct {
+	upstream map[string]map[string]string
+	branches map[string]map[string]string
+	pulls    []map[string]string
+}
+
+func (f *fakeGitea) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Authorization") != "token secret" {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	body := map[string]interface{}{}
+	if r.Body != nil {
+		json.NewDecoder(r.Body).Decode(&body)
+	}
+	str := func(k string) string { s, _ := body[k].(string); return s }
+
+	path := r.URL.Path
+	switch {
+	case r.Method == http.MethodGet && path == "/api/v1/repos/platform/fish-food":
+		json.NewEncoder(w).Encode(map[string]string{"default_branch": "master"})
+	case r.Method == http.MethodPost && path == "/api/v1/repos/bot/fish-food/merge-upstream":
+		f.branches[str("branch")] = copyFiles(f.upstream[str("branch")])
+		json.NewEncoder(w).Encode(map[string]string{"merge_type": "fast-forward"})
+	case r.Method == http.MethodPost && path == "/api/v1/repos/bot/fish-food/branches":
+		f.branches[str("new_branch_name")] = copyFiles(f.branches[str("old_branch_name")])
+	case r.Method == http.MethodGet && strings.HasPrefix(path, "/api/v1/repos/platform/fish-food/contents/"):
+		// Directories are listed with the files and directories they contain
+		dir := strings.TrimPrefix(path, "/api/v1/repos/platform/fish-food/contents/") + "/"
+		entries := []map[string]string{}
+		for file := range f.upstream[r.URL.Query().Get("ref")] {
+			if name := strings.TrimPrefix(file, dir); name != file {
+				entries = append(entries, map[string]string{"name": name, "type": "file"})
+			}
+		}
+		if len(entries) == 0 {
+			http.NotFound(w, r)
+			return
+		}
+		sort.Slice(entries, func(i, j int) bool { return entries[i]["name"] < entries[j]["name"] })
+		entries = append(entries, map[string]string{"name": "nested", "type": "dir"})
+		json.NewEncoder(w).Encode(entries)
+	case strings.HasPrefix(path, "/api/v1/repos/bot/fish-food/contents/"):
+		file := strings.TrimPrefix(path, "/api/v1/repos/bot/fish-food/contents/")
+		if r.Method == http.MethodGet {
+			content, ok := f.branches[r.URL.Query().Get("ref")][file]
+			if !ok {
+				http.NotFound(w, r)
+				return
+			}
+			json.NewEncoder(w).Encode(giteaFile{SHA: "sha-" + content, Content: base64.StdEncoding.EncodeToString([]byte(content))})
+			return
+		}
+		files := f.branches[str("branch")]
+		_, exists := files[file]
+		if exists != (r.Method == http.MethodPut) || (exists && str("sha") != "sha-"+files[file]) {
+			http.Error(w, "conflict", http.StatusUnprocessableEntity)
+			return
+		}
+		content, _ := base64.StdEncoding.DecodeString(str("content"))
+		files[file] = string(content)
+	case r.Method == http.MethodPost && path == "/api/v1/repos/platform/fish-food/pulls":
+		f.pulls = append(f.pulls, map[string]string{"head": str("head"), "base": str("base"), "title": str("title")})
+		json.NewEncoder(w).Encode(map[string]string{"html_url": "https://gitea.example.com/platform/fish-food/pulls/1"})
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func copyFiles(files map[string]string) map[string]string {
+	c := map[string]string{}
+	for k, v := range files {
+		c[k] = v
+	}
+	return c
+}
+
+func TestGitea(t *testing.T) {
+	// The fork is behind the repository
+	fake := &fakeGitea{
+		upstream: map[string]map[string]string{
+			"master": {"Food/tool.lua": "old", "Food/added.lua": "added"},
+		},
+		branches: map[string]map[string]string{
+			"master": {"Food/tool.lua": "old"},
+		},
+	}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	ctx := context.Background()
+	g := &Gitea{URL: server.URL, Token: "secret", Owner: "platform", Repo: "fish-food", Fork: "bot", Client: server.Client()}
+
+	files, err := g.ListFiles(ctx, "master", "Food")
+	if err != nil || !reflect.DeepEqual(files, []string{"added.lua", "tool.lua"}) {
+		t.Errorf("ListFiles() = %v, %v, want [added.lua tool.lua]", files, err)
+	}
+
+	if err := g.CreateBranch(ctx, "tool-1.0.0"); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.WriteFile(ctx, "tool-1.0.0", "Food/tool.lua", []byte("new"), "tool 1.0.0"); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.WriteFile(ctx, "tool-1.0.0", "Food/other.lua", []byte("other"), "other 1.0.0"); err != nil {
+		t.Fatal(err)
+	}
+	link, err := g.OpenPullRequest(ctx, PullRequest{Branch: "tool-1.0.0", Title: "tool 1.0.0"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := fake.branches["tool-1.0.0"]; got["Food/tool.lua"] != "new" || got["Food/other.lua"] != "other" || got["Food/added.lua"] != "added" {
+		t.Errorf("branch files = %v", got)
+	}
+	if got := fake.branches["master"]["Food/tool.lua"]; got != "old" {
+		t.Errorf("master Food/tool.lua = %q, want old", got)
+	}
+	if len(fake.pulls) != 1 || fake.pulls[0]["head"] != "bot:tool-1.0.0" || fake.pulls[0]["base"] != "master" {
+		t.Errorf("pulls = %v", fake.pulls)
+	}
+	if link != "https://gitea.example.com/platform/fish-food/pulls/1" {
+		t.Errorf("OpenPullRequest() = %s", link)
+	}
+
+	g.Token = "wrong"
+	if err := g.CreateBranch(ctx, "other"); err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("CreateBranch() with a wrong token = %v, want 401", err)
+	}
+}
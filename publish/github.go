@@ -0,0 +1,156 @@
+package publish
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	ghApi "github.com/google/go-github/v32/github"
+
+	"github.com/gofish-bot/gofish-bot/log"
+)
+
+// GitHub publishes to the fork of the bot through the GitHub REST API and
+// opens pull requests against the upstream repository
+type GitHub struct {
+	Client *ghApi.Client
+	// Owner is the owner of the fork, usually the bot
+	Owner string
+	// Upstream is the owner of the repository pull requests are opened in
+	Upstream string
+	Repo     string
+	// Base is the branch pull requests are opened against, by default the
+	// default branch of upstream
+	Base   string
+	Author Author
+}
+
+// BaseBranch returns the base branch, discovering the default branch of upstream
+// when not set
+func (g *GitHub) BaseBranch(ctx context.Context) (string, error) {
+	if g.Base == "" {
+		repo, _, err := g.Client.Repositories.Get(ctx, g.Upstream, g.Repo)
+		if err != nil {
+			return "", fmt.Errorf("Error getting default branch of %s/%s: %v", g.Upstream, g.Repo, err)
+		}
+		g.Base = repo.GetDefaultBranch()
+	}
+	return g.Base, nil
+}
+
+// CreateBranch syncs the base branch of the fork with upstream and creates
+// the branch from it. When the fork has diverged and can not be synced, the
+// branch is created from the upstream commit instead.
+func (g *GitHub) CreateBranch(ctx context.Context, branch string) error {
+	log.G(ctx).Debugf("Creating new Branch %s", branch)
+	base, err := g.BaseBranch(ctx)
+	if err != nil {
+		return err
+	}
+
+	owner := g.Owner
+	if g.Owner != g.Upstream {
+		err := g.syncFork(ctx, base)
+		switch {
+		case err == nil:
+		case isDiverged(err):
+			log.G(ctx).Warnf("Could not sync %s/%s with %s, branching from upstream: %v", g.Owner, g.Repo, g.Upstream, err)
+			owner = g.Upstream
+		default:
+			return fmt.Errorf("Error syncing %s/%s with %s: %v", g.Owner, g.Repo, g.Upstream, err)
+		}
+	}
+
+	ref, _, err := g.Client.Git.GetRef(ctx, owner, g.Repo, "refs/heads/"+base)
+	if err != nil {
+		return fmt.Errorf("Error getting ref %s of %s/%s: %v", base, owner, g.Repo, err)
+	}
+
+	// Forks share their objects with upstream, so upstream commits can be
+	// referenced in the fork
+	_, _, err = g.Client.Git.CreateRef(ctx, g.Owner, g.Repo, &ghApi.Reference{
+		Ref:    ghApi.String("refs/heads/" + branch),
+		Object: ref.GetObject(),
+	})
+	return err
+}
+
+// syncFork fast-forwards or merges the base branch of the fork with upstream
+func (g *GitHub) syncFork(ctx context.Context, base string) error {
+	u := fmt.Sprintf("repos/%s/%s/merge-upstream", g.Owner, g.Repo)
+	req, err := g.Client.NewRequest(http.MethodPost, u, map[string]string{"branch": base})
+	if err != nil {
+		return err
+	}
+
+	res := struct {
+		Message   string `json:"message"`
+		MergeType string `json:"merge_type"`
+	}{}
+	_, err = g.Client.Do(ctx, req, &res)
+	if err != nil {
+		return err
+	}
+	log.G(ctx).Debugf("Synced %s/%s: %s %s", g.Owner, g.Repo, res.MergeType, res.Message)
+	return nil
+}
+
+// isDiverged reports whether merge-upstream failed because the fork can not
+// be merged with upstream, in which case branching from upstream is safe
+func isDiverged(err error) bool {
+	errResp, ok := err.(*ghApi.ErrorResponse)
+	if !ok || errResp.Response == nil {
+		return false
+	}
+	return errResp.Response.StatusCode == http.StatusConflict || errResp.Response.StatusCode == http.StatusUnprocessableEntity
+}
+
+// WriteFile creates or updates the file in the branch of the fork
+func (g *GitHub) WriteFile(ctx context.Context, branch, path string, content []byte, message string) error {
+	author := &ghApi.CommitAuthor{Name: ghApi.String(g.Author.Name), Email: ghApi.String(g.Author.Email)}
+	opts := &ghApi.RepositoryContentFileOptions{
+		Message:   ghApi.String(message),
+		Content:   content,
+		Branch:    ghApi.String(branch),
+		Author:    author,
+		Committer: author,
+	}
+
+	getOpts := &ghApi.RepositoryContentGetOptions{Ref: branch}
+	res, _, resp, err := g.Client.Repositories.GetContents(ctx, g.Owner, g.Repo, path, getOpts)
+	switch {
+	case err == nil:
+		log.G(ctx).Debugf("Updating %s", path)
+		opts.SHA = ghApi.String(res.GetSHA())
+	case resp != nil && resp.StatusCode == http.StatusNotFound:
+		log.G(ctx).Debugf("Creating %s", path)
+	default:
+		return err
+	}
+
+	_, _, err = g.Client.Repositories.UpdateFile(ctx, g.Owner, g.Repo, path, opts)
+	return err
+}
+
+// OpenPullRequest opens a pull request from the branch of the fork into the
+// base branch of the upstream repository
+func (g *GitHub) OpenPullRequest(ctx context.Context, pr PullRequest) (string, error) {
+	log.G(ctx).Debugf("Sending pull request")
+	base, err := g.BaseBranch(ctx)
+	if err != nil {
+		return "", err
+	}
+	newPR := &ghApi.NewPullRequest{
+		Title:               ghApi.String(pr.Title),
+		Head:                ghApi.String(g.Owner + ":" + pr.Branch),
+		Base:                ghApi.String(base),
+		Body:                ghApi.String(pr.Body),
+		MaintainerCanModify: ghApi.Bool(true),
+	}
+
+	created, _, err := g.Client.PullRequests.Create(ctx, g.Upstream, g.Repo, newPR)
+	if err != nil {
+		return "", err
+	}
+	return created.GetHTMLURL(), nil
+}
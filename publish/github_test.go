@@ -0,0 +1,98 @@
+package publish
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	ghApi "github.com/google/go-github/v32/github"
+)
+
+func TestGitHub_CreateBranch(t *testing.T) {
+	tests := []struct {
+		name       string
+		syncStatus int
+		want       string
+		wantErr    bool
+	}{
+		{"synced fork", http.StatusOK, "synced", false},
+		{"diverged fork", http.StatusConflict, "upstream", false},
+		{"unmergeable fork", http.StatusUnprocessableEntity, "upstream", false},
+		{"unauthorized", http.StatusUnauthorized, "", true},
+		{"server error", http.StatusInternalServerError, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			heads := map[string]string{
+				"bot/fish-food":       "stale",
+				"fishworks/fish-food": "upstream",
+			}
+			var synced bool
+			var created map[string]string
+
+			mux := http.NewServeMux()
+			mux.HandleFunc("/repos/fishworks/fish-food", func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte(`{"default_branch": "main"}`))
+			})
+			mux.HandleFunc("/repos/bot/fish-food/merge-upstream", func(w http.ResponseWriter, r *http.Request) {
+				body := map[string]string{}
+				json.NewDecoder(r.Body).Decode(&body)
+				if r.Method != http.MethodPost || body["branch"] != "main" {
+					t.Errorf("merge-upstream %s %v", r.Method, body)
+				}
+				w.WriteHeader(tt.syncStatus)
+				if tt.syncStatus == http.StatusOK {
+					synced = true
+					heads["bot/fish-food"] = "synced"
+					w.Write([]byte(`{"message": "Successfully fetched and fast-forwarded", "merge_type": "fast-forward"}`))
+				} else {
+					w.Write([]byte(`{"message": "There are merge conflicts"}`))
+				}
+			})
+			ref := func(repo string) http.HandlerFunc {
+				return func(w http.ResponseWriter, r *http.Request) {
+					json.NewEncoder(w).Encode(ghApi.Reference{
+						Ref:    ghApi.String("refs/heads/main"),
+						Object: &ghApi.GitObject{SHA: ghApi.String(heads[repo])},
+					})
+				}
+			}
+			mux.HandleFunc("/repos/bot/fish-food/git/ref/heads/main", ref("bot/fish-food"))
+			mux.HandleFunc("/repos/fishworks/fish-food/git/ref/heads/main", ref("fishworks/fish-food"))
+			mux.HandleFunc("/repos/bot/fish-food/git/refs", func(w http.ResponseWriter, r *http.Request) {
+				json.NewDecoder(r.Body).Decode(&created)
+				w.WriteHeader(http.StatusCreated)
+				json.NewEncoder(w).Encode(ghApi.Reference{
+					Ref:    ghApi.String(created["ref"]),
+					Object: &ghApi.GitObject{SHA: ghApi.String(created["sha"])},
+				})
+			})
+			server := httptest.NewServer(mux)
+			defer server.Close()
+
+			client := ghApi.NewClient(server.Client())
+			client.BaseURL, _ = url.Parse(server.URL + "/")
+			g := &GitHub{Client: client, Owner: "bot", Upstream: "fishworks", Repo: "fish-food"}
+
+			err := g.CreateBranch(context.Background(), "tool-1.0.0")
+			if tt.wantErr {
+				if err == nil || created != nil {
+					t.Errorf("CreateBranch() = %v, created %v, want an error and no branch", err, created)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if synced != (tt.syncStatus == http.StatusOK) {
+				t.Errorf("synced = %v", synced)
+			}
+			if created["ref"] != "refs/heads/tool-1.0.0" || created["sha"] != tt.want {
+				t.Errorf("created ref = %v, want refs/heads/tool-1.0.0 at %s", created, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,190 @@
+package publish
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/gofish-bot/gofish-bot/log"
+)
+
+// gitLabPageSize is the number of entries requested per page of listings
+var gitLabPageSize = 100
+
+// GitLab publishes to a project on a GitLab server through its REST API and
+// opens merge requests
+type GitLab struct {
+	// URL of the GitLab server, eg. https://gitlab.example.com
+	URL   string
+	Token string
+	// Owner is the group or user and Repo the name of the project merge
+	// requests are opened in
+	Owner string
+	Repo  string
+	// Fork is the group or user of the fork branches are pushed to. Branches
+	// are pushed to the project itself when empty.
+	Fork string
+	// Base is the branch merge requests are opened against, by default the
+	// default branch of the project
+	Base   string
+	Author Author
+	Client *http.Client
+}
+
+// BaseBranch returns the base branch, discovering the default branch of the
+// project when not set
+func (g *GitLab) BaseBranch(ctx context.Context) (string, error) {
+	if g.Base == "" {
+		project := struct {
+			DefaultBranch string `json:"default_branch"`
+		}{}
+		if err := g.api().do(ctx, http.MethodGet, g.project(g.Owner), nil, &project); err != nil {
+			return "", fmt.Errorf("Error getting default branch of %s/%s: %v", g.Owner, g.Repo, err)
+		}
+		g.Base = project.DefaultBranch
+	}
+	return g.Base, nil
+}
+
+// project returns the path of the project in the API of the owner
+func (g *GitLab) project(owner string) string {
+	return "/projects/" + url.PathEscape(owner+"/"+g.Repo)
+}
+
+// source returns the path of the project branches are pushed to
+func (g *GitLab) source() string {
+	if g.Fork == "" {
+		return g.project(g.Owner)
+	}
+	return g.project(g.Fork)
+}
+
+func (g *GitLab) api() *restClient {
+	return &restClient{
+		client:  g.Client,
+		baseURL: g.URL + "/api/v4",
+		header:  http.Header{"Private-Token": {g.Token}},
+	}
+}
+
+// CreateBranch creates the branch in the fork, or the project. Branches in
+// the fork are created from the commit of the base branch of the project,
+// as the base branch of the fork may be behind.
+func (g *GitLab) CreateBranch(ctx context.Context, branch string) error {
+	log.G(ctx).Debugf("Creating new Branch %s", branch)
+	base, err := g.BaseBranch(ctx)
+	if err != nil {
+		return err
+	}
+
+	ref := base
+	if g.Fork != "" && g.Fork != g.Owner {
+		// Forks share the objects of the project, so its commits can be
+		// referenced in the fork
+		baseBranch := struct {
+			Commit struct {
+				ID string `json:"id"`
+			} `json:"commit"`
+		}{}
+		if err := g.api().do(ctx, http.MethodGet, g.project(g.Owner)+"/repository/branches/"+url.PathEscape(base), nil, &baseBranch); err != nil {
+			return fmt.Errorf("Error getting branch %s of %s/%s: %v", base, g.Owner, g.Repo, err)
+		}
+		ref = baseBranch.Commit.ID
+	}
+	q := url.Values{"branch": {branch}, "ref": {ref}}
+	return g.api().do(ctx, http.MethodPost, g.source()+"/repository/branches?"+q.Encode(), nil, nil)
+}
+
+// WriteFile commits the file to the branch, creating it if it does not exist
+func (g *GitLab) WriteFile(ctx context.Context, branch, path string, content []byte, message string) error {
+	action := "update"
+	err := g.api().do(ctx, http.MethodHead, g.source()+"/repository/files/"+url.PathEscape(path)+"?ref="+url.QueryEscape(branch), nil, nil)
+	if isNotFound(err) {
+		action = "create"
+	} else if err != nil {
+		return err
+	}
+	log.G(ctx).Debugf("Commit %s %s", action, path)
+
+	in := map[string]interface{}{
+		"branch":         branch,
+		"commit_message": message,
+		"author_name":    g.Author.Name,
+		"author_email":   g.Author.Email,
+		"actions": []map[string]string{{
+			"action":    action,
+			"file_path": path,
+			"content":   base64.StdEncoding.EncodeToString(content),
+			"encoding":  "base64",
+		}},
+	}
+	return g.api().do(ctx, http.MethodPost, g.source()+"/repository/commits", in, nil)
+}
+
+// OpenPullRequest opens a merge request into the base branch of the project
+func (g *GitLab) OpenPullRequest(ctx context.Context, pr PullRequest) (string, error) {
+	log.G(ctx).Debugf("Sending merge request")
+	base, err := g.BaseBranch(ctx)
+	if err != nil {
+		return "", err
+	}
+	in := map[string]interface{}{
+		"source_branch": pr.Branch,
+		"target_branch": base,
+		"title":         pr.Title,
+		"description":   pr.Body,
+	}
+	if g.Fork != "" {
+		target := struct {
+			ID int `json:"id"`
+		}{}
+		if err := g.api().do(ctx, http.MethodGet, g.project(g.Owner), nil, &target); err != nil {
+			return "", fmt.Errorf("Error getting project %s/%s: %v", g.Owner, g.Repo, err)
+		}
+		in["target_project_id"] = target.ID
+	}
+
+	out := struct {
+		WebURL string `json:"web_url"`
+	}{}
+	err = g.api().do(ctx, http.MethodPost, g.source()+"/merge_requests", in, &out)
+	return out.WebURL, err
+}
+
+// ReadFile reads the file at path in ref of the project
+func (g *GitLab) ReadFile(ctx context.Context, ref, path string) ([]byte, error) {
+	file := struct {
+		Content string `json:"content"`
+	}{}
+	err := g.api().do(ctx, http.MethodGet, g.project(g.Owner)+"/repository/files/"+url.PathEscape(path)+"?ref="+url.QueryEscape(ref), nil, &file)
+	if err != nil {
+		return nil, err
+	}
+	return base64.StdEncoding.DecodeString(file.Content)
+}
+
+// ListFiles lists the files in the directory dir in ref of the project
+func (g *GitLab) ListFiles(ctx context.Context, ref, dir string) ([]string, error) {
+	names := []string{}
+	for page := 1; ; page++ {
+		entries := []struct {
+			Name string `json:"name"`
+			Type string `json:"type"`
+		}{}
+		query := fmt.Sprintf("?path=%s&ref=%s&per_page=%d&page=%d", url.QueryEscape(dir), url.QueryEscape(ref), gitLabPageSize, page)
+		err := g.api().do(ctx, http.MethodGet, g.project(g.Owner)+"/repository/tree"+query, nil, &entries)
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			if entry.Type == "blob" {
+				names = append(names, entry.Name)
+			}
+		}
+		if len(entries) < gitLabPageSize {
+			return names, nil
+		}
+	}
+}
@@ -0,0 +1,186 @@
+package publish
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// fakeGitLab serves the part of the GitLab API used by the publisher, for a
+// project and the fork of the bot with the files of each branch kept in
+// memory. The commit of a branch of the project is its name prefixed with
+// "sha-".
+type fakeGitLab struct {
+	branches      map[string]map[string]string
+	fork          map[string]map[string]string
+	mergeRequests []map[string]interface{}
+}
+
+func (f *fakeGitLab) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Private-Token") != "secret" {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	body := map[string]interface{}{}
+	if r.Body != nil {
+		json.NewDecoder(r.Body).Decode(&body)
+	}
+
+	// The project and file paths must be escaped
+	const project = "/api/v4/projects/platform%2Ffish-food"
+	const fork = "/api/v4/projects/bot%2Ffish-food"
+	path := r.URL.EscapedPath()
+	q := r.URL.Query()
+	switch {
+	case r.Method == http.MethodGet && path == project:
+		json.NewEncoder(w).Encode(map[string]interface{}{"id": 1, "default_branch": "main"})
+	case r.Method == http.MethodPost && path == project+"/repository/branches":
+		f.branches[q.Get("branch")] = copyFiles(f.branches[q.Get("ref")])
+	case r.Method == http.MethodGet && strings.HasPrefix(path, project+"/repository/branches/"):
+		name := strings.TrimPrefix(path, project+"/repository/branches/")
+		if _, ok := f.branches[name]; !ok {
+			http.NotFound(w, r)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"commit": map[string]string{"id": "sha-" + name}})
+	case r.Method == http.MethodPost && path == fork+"/repository/branches":
+		// Branches of the fork can only be created from commits of the project
+		files, ok := f.branches[strings.TrimPrefix(q.Get("ref"), "sha-")]
+		if !ok || !strings.HasPrefix(q.Get("ref"), "sha-") {
+			http.Error(w, "invalid reference name", http.StatusBadRequest)
+			return
+		}
+		f.fork[q.Get("branch")] = copyFiles(files)
+	case strings.HasPrefix(path, project+"/repository/files/"):
+		file := strings.ReplaceAll(strings.TrimPrefix(path, project+"/repository/files/"), "%2F", "/")
+		content, ok := f.branches[q.Get("ref")][file]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"content": base64.StdEncoding.EncodeToString([]byte(content))})
+	case r.Method == http.MethodGet && path == project+"/repository/tree":
+		// Directories are listed first
+		entries := []map[string]string{{"name": "nested", "type": "tree"}}
+		for file := range f.branches[q.Get("ref")] {
+			if name := strings.TrimPrefix(file, q.Get("path")+"/"); name != file {
+				entries = append(entries, map[string]string{"name": name, "type": "blob"})
+			}
+		}
+		sort.Slice(entries[1:], func(i, j int) bool { return entries[i+1]["name"] < entries[j+1]["name"] })
+		page, _ := strconv.Atoi(q.Get("page"))
+		perPage, _ := strconv.Atoi(q.Get("per_page"))
+		start, end := (page-1)*perPage, page*perPage
+		if start > len(entries) {
+			start = len(entries)
+		}
+		if end > len(entries) {
+			end = len(entries)
+		}
+		json.NewEncoder(w).Encode(entries[start:end])
+	case r.Method == http.MethodPost && path == project+"/repository/commits":
+		files := f.branches[body["branch"].(string)]
+		for _, a := range body["actions"].([]interface{}) {
+			action := a.(map[string]interface{})
+			file := action["file_path"].(string)
+			if _, exists := files[file]; exists != (action["action"] == "update") {
+				http.Error(w, "wrong action", http.StatusBadRequest)
+				return
+			}
+			content, _ := base64.StdEncoding.DecodeString(action["content"].(string))
+			files[file] = string(content)
+		}
+	case r.Method == http.MethodPost && path == project+"/merge_requests":
+		f.mergeRequests = append(f.mergeRequests, body)
+		json.NewEncoder(w).Encode(map[string]string{"web_url": "https://gitlab.example.com/platform/fish-food/-/merge_requests/1"})
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func TestGitLab(t *testing.T) {
+	fake := &fakeGitLab{branches: map[string]map[string]string{
+		"main": {"Food/tool.lua": "old", "Food/a.lua": "a", "Food/b.lua": "b"},
+	}}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	ctx := context.Background()
+	g := &GitLab{URL: server.URL, Token: "secret", Owner: "platform", Repo: "fish-food", Client: server.Client()}
+
+	content, err := g.ReadFile(ctx, "main", "Food/tool.lua")
+	if err != nil || string(content) != "old" {
+		t.Errorf("ReadFile() = %q, %v, want old", content, err)
+	}
+
+	// The listing is paged
+	defer func(size int) { gitLabPageSize = size }(gitLabPageSize)
+	gitLabPageSize = 2
+	files, err := g.ListFiles(ctx, "main", "Food")
+	if err != nil || !reflect.DeepEqual(files, []string{"a.lua", "b.lua", "tool.lua"}) {
+		t.Errorf("ListFiles() = %v, %v, want [a.lua b.lua tool.lua]", files, err)
+	}
+
+	if err := g.CreateBranch(ctx, "tool-1.0.0"); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.WriteFile(ctx, "tool-1.0.0", "Food/tool.lua", []byte("new"), "tool 1.0.0"); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.WriteFile(ctx, "tool-1.0.0", "Food/other.lua", []byte("other"), "other 1.0.0"); err != nil {
+		t.Fatal(err)
+	}
+	link, err := g.OpenPullRequest(ctx, PullRequest{Branch: "tool-1.0.0", Title: "tool 1.0.0", Body: "body"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := fake.branches["tool-1.0.0"]; got["Food/tool.lua"] != "new" || got["Food/other.lua"] != "other" {
+		t.Errorf("branch files = %v", got)
+	}
+	if got := fake.branches["main"]["Food/tool.lua"]; got != "old" {
+		t.Errorf("main Food/tool.lua = %q, want old", got)
+	}
+	if len(fake.mergeRequests) != 1 {
+		t.Fatalf("opened %d merge requests, want 1", len(fake.mergeRequests))
+	}
+	mr := fake.mergeRequests[0]
+	if mr["source_branch"] != "tool-1.0.0" || mr["target_branch"] != "main" || mr["description"] != "body" {
+		t.Errorf("merge request = %v", mr)
+	}
+	if _, ok := mr["target_project_id"]; ok {
+		t.Errorf("merge request without fork has a target project: %v", mr)
+	}
+	if link != "https://gitlab.example.com/platform/fish-food/-/merge_requests/1" {
+		t.Errorf("OpenPullRequest() = %s", link)
+	}
+}
+
+func TestGitLab_CreateBranch_fork(t *testing.T) {
+	// The fork is behind the project
+	fake := &fakeGitLab{
+		branches: map[string]map[string]string{
+			"main": {"Food/tool.lua": "old", "Food/added.lua": "added"},
+		},
+		fork: map[string]map[string]string{
+			"main": {"Food/tool.lua": "old"},
+		},
+	}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	g := &GitLab{URL: server.URL, Token: "secret", Owner: "platform", Repo: "fish-food", Fork: "bot", Client: server.Client()}
+	if err := g.CreateBranch(context.Background(), "tool-1.0.0"); err != nil {
+		t.Fatal(err)
+	}
+	if got := fake.fork["tool-1.0.0"]; !reflect.DeepEqual(got, fake.branches["main"]) {
+		t.Errorf("fork branch files = %v, want the files of the project", got)
+	}
+}
@@ -0,0 +1,53 @@
+// Package publish creates the branches, commits and pull requests updating
+// foods in a fish-food repository
+package publish
+
+import "context"
+
+// Publisher publishes changes to a fish-food repository
+type Publisher interface {
+	// BaseBranch returns the branch pull requests are opened against, by default
+	// the default branch of the repository
+	BaseBranch(ctx context.Context) (string, error)
+	// CreateBranch creates the branch from the head of the base branch
+	CreateBranch(ctx context.Context, branch string) error
+	// WriteFile commits the content to the file at path in the branch,
+	// creating the file if it does not exist
+	WriteFile(ctx context.Context, branch, path string, content []byte, message string) error
+	// OpenPullRequest proposes merging the branch into the base branch, and
+	// returns a link to the pull request
+	OpenPullRequest(ctx context.Context, pr PullRequest) (string, error)
+}
+
+// Reader is implemented by publishers that can read the foods of the
+// repository they publish to
+type Reader interface {
+	// ReadFile returns the content of the file at path in ref
+	ReadFile(ctx context.Context, ref, path string) ([]byte, error)
+	// ListFiles returns the names of the files in the directory dir in ref,
+	// without subdirectories
+	ListFiles(ctx context.Context, ref, dir string) ([]string, error)
+}
+
+// PullRequest describes a pull request from a branch into the base branch
+type PullRequest struct {
+	Branch string
+	Title  string
+	Body   string
+}
+
+// Author is the author and committer of the commits
+type Author struct {
+	Name  string
+	Email string
+}
+
+var (
+	_ Publisher = &GitHub{}
+	_ Publisher = &Git{}
+	_ Publisher = &Gitea{}
+	_ Publisher = &GitLab{}
+	_ Reader    = &Git{}
+	_ Reader    = &Gitea{}
+	_ Reader    = &GitLab{}
+)
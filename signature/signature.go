@@ -0,0 +1,165 @@
+package signature
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+
+	"golang.org/x/crypto/blake2b"
+	"golang.org/x/crypto/openpgp"
+)
+
+// Supported signing methods
+const (
+	Cosign   = "cosign"
+	GPG      = "gpg"
+	Minisign = "minisign"
+)
+
+// Extensions returns the file extensions used for signatures of the method,
+// in order of preference
+func Extensions(method string) []string {
+	switch method {
+	case Cosign:
+		return []string{".sig"}
+	case GPG:
+		return []string{".asc", ".sig", ".gpg"}
+	case Minisign:
+		return []string{".minisig"}
+	}
+	return nil
+}
+
+// Verify checks that sig is a valid signature of content made by the owner
+// of the trusted public key
+func Verify(method string, key []byte, content io.Reader, sig []byte) error {
+	switch method {
+	case Cosign:
+		return verifyCosign(key, content, sig)
+	case GPG:
+		return verifyGPG(key, content, sig)
+	case Minisign:
+		return verifyMinisign(key, content, sig)
+	}
+	return fmt.Errorf("unknown signing method: '%s'", method)
+}
+
+// verifyCosign verifies a signature created by `cosign sign-blob` with a
+// ECDSA key pair. The signature is the base64 encoded ASN.1 signature of the
+// sha256 of the content.
+func verifyCosign(key []byte, content io.Reader, sig []byte) error {
+	block, _ := pem.Decode(key)
+	if block == nil {
+		return fmt.Errorf("cosign public key is not PEM encoded")
+	}
+	pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return fmt.Errorf("could not parse cosign public key: %v", err)
+	}
+	ecdsaKey, ok := pub.(*ecdsa.PublicKey)
+	if !ok {
+		return fmt.Errorf("cosign public key is not an ECDSA key")
+	}
+
+	rawSig, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(sig)))
+	if err != nil {
+		return fmt.Errorf("could not decode cosign signature: %v", err)
+	}
+
+	h := sha256.New()
+	if _, err := io.Copy(h, content); err != nil {
+		return err
+	}
+	if !ecdsa.VerifyASN1(ecdsaKey, h.Sum(nil), rawSig) {
+		return fmt.Errorf("cosign signature does not match")
+	}
+	return nil
+}
+
+// verifyGPG verifies an armored or binary detached OpenPGP signature
+func verifyGPG(key []byte, content io.Reader, sig []byte) error {
+	keyring, err := openpgp.ReadArmoredKeyRing(bytes.NewReader(key))
+	if err != nil {
+		keyring, err = openpgp.ReadKeyRing(bytes.NewReader(key))
+		if err != nil {
+			return fmt.Errorf("could not read gpg public key: %v", err)
+		}
+	}
+
+	if bytes.HasPrefix(bytes.TrimSpace(sig), []byte("-----BEGIN PGP")) {
+		_, err = openpgp.CheckArmoredDetachedSignature(keyring, content, bytes.NewReader(sig))
+	} else {
+		_, err = openpgp.CheckDetachedSignature(keyring, content, bytes.NewReader(sig))
+	}
+	if err != nil {
+		return fmt.Errorf("gpg signature does not match: %v", err)
+	}
+	return nil
+}
+
+// verifyMinisign verifies a minisign signature, both the legacy and the
+// prehashed format, including the signature of the trusted comment
+func verifyMinisign(key []byte, content io.Reader, sig []byte) error {
+	rawKey, err := base64.StdEncoding.DecodeString(lastLine(string(key)))
+	if err != nil || len(rawKey) != 42 || string(rawKey[:2]) != "Ed" {
+		return fmt.Errorf("could not parse minisign public key")
+	}
+	keyID := rawKey[2:10]
+	pub := ed25519.PublicKey(rawKey[10:])
+
+	lines := strings.Split(strings.TrimSpace(string(sig)), "\n")
+	if len(lines) < 4 {
+		return fmt.Errorf("minisign signature is incomplete")
+	}
+	rawSig, err := base64.StdEncoding.DecodeString(strings.TrimSpace(lines[1]))
+	if err != nil || len(rawSig) != 74 {
+		return fmt.Errorf("could not parse minisign signature")
+	}
+	if !bytes.Equal(rawSig[2:10], keyID) {
+		return fmt.Errorf("minisign signature was made with another key")
+	}
+
+	var message []byte
+	switch string(rawSig[:2]) {
+	case "Ed":
+		message, err = ioutil.ReadAll(content)
+		if err != nil {
+			return err
+		}
+	case "ED":
+		h, _ := blake2b.New512(nil)
+		if _, err := io.Copy(h, content); err != nil {
+			return err
+		}
+		message = h.Sum(nil)
+	default:
+		return fmt.Errorf("unknown minisign signature algorithm")
+	}
+	if !ed25519.Verify(pub, message, rawSig[10:]) {
+		return fmt.Errorf("minisign signature does not match")
+	}
+
+	trustedComment := strings.TrimPrefix(strings.TrimSpace(lines[2]), "trusted comment: ")
+	globalSig, err := base64.StdEncoding.DecodeString(strings.TrimSpace(lines[3]))
+	if err != nil {
+		return fmt.Errorf("could not parse minisign global signature")
+	}
+	signed := append(append([]byte{}, rawSig[10:]...), []byte(trustedComment)...)
+	if !ed25519.Verify(pub, signed, globalSig) {
+		return fmt.Errorf("minisign trusted comment signature does not match")
+	}
+	return nil
+}
+
+func lastLine(s string) string {
+	lines := strings.Split(strings.TrimSpace(s), "\n")
+	return strings.TrimSpace(lines[len(lines)-1])
+}
@@ -0,0 +1,111 @@
+package signature
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"testing"
+
+	"golang.org/x/crypto/blake2b"
+	"golang.org/x/crypto/openpgp"
+)
+
+var content = []byte("0123abcd  tool_linux_amd64.tar.gz\n")
+
+func TestVerifyCosign(t *testing.T) {
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(&priv.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+
+	digest := sha256.Sum256(content)
+	rawSig, err := ecdsa.SignASN1(rand.Reader, priv, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig := []byte(base64.StdEncoding.EncodeToString(rawSig))
+
+	if err := Verify(Cosign, key, bytes.NewReader(content), sig); err != nil {
+		t.Errorf("Verify() error = %v", err)
+	}
+	if err := Verify(Cosign, key, bytes.NewReader([]byte("tampered")), sig); err == nil {
+		t.Errorf("Verify() accepted tampered content")
+	}
+}
+
+func TestVerifyGPG(t *testing.T) {
+	entity, err := openpgp.NewEntity("gofish-bot", "", "bot@example.com", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var key bytes.Buffer
+	if err := entity.Serialize(&key); err != nil {
+		t.Fatal(err)
+	}
+
+	var armored bytes.Buffer
+	if err := openpgp.ArmoredDetachSign(&armored, entity, bytes.NewReader(content), nil); err != nil {
+		t.Fatal(err)
+	}
+	var binary bytes.Buffer
+	if err := openpgp.DetachSign(&binary, entity, bytes.NewReader(content), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, sig := range map[string][]byte{"armored": armored.Bytes(), "binary": binary.Bytes()} {
+		if err := Verify(GPG, key.Bytes(), bytes.NewReader(content), sig); err != nil {
+			t.Errorf("Verify() %s error = %v", name, err)
+		}
+		if err := Verify(GPG, key.Bytes(), bytes.NewReader([]byte("tampered")), sig); err == nil {
+			t.Errorf("Verify() %s accepted tampered content", name)
+		}
+	}
+}
+
+func minisign(t *testing.T, algorithm string, priv ed25519.PrivateKey, keyID []byte, message []byte) []byte {
+	if algorithm == "ED" {
+		h := blake2b.Sum512(message)
+		message = h[:]
+	}
+	rawSig := append(append([]byte(algorithm), keyID...), ed25519.Sign(priv, message)...)
+	trustedComment := "timestamp:1625000000"
+	globalSig := ed25519.Sign(priv, append(append([]byte{}, rawSig[10:]...), []byte(trustedComment)...))
+	return []byte(fmt.Sprintf("untrusted comment: signature\n%s\ntrusted comment: %s\n%s\n",
+		base64.StdEncoding.EncodeToString(rawSig), trustedComment, base64.StdEncoding.EncodeToString(globalSig)))
+}
+
+func TestVerifyMinisign(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyID := []byte{1, 2, 3, 4, 5, 6, 7, 8}
+	key := []byte("untrusted comment: minisign public key\n" + base64.StdEncoding.EncodeToString(append(append([]byte("Ed"), keyID...), pub...)) + "\n")
+
+	for _, algorithm := range []string{"Ed", "ED"} {
+		sig := minisign(t, algorithm, priv, keyID, content)
+		if err := Verify(Minisign, key, bytes.NewReader(content), sig); err != nil {
+			t.Errorf("Verify() %s error = %v", algorithm, err)
+		}
+		if err := Verify(Minisign, key, bytes.NewReader([]byte("tampered")), sig); err == nil {
+			t.Errorf("Verify() %s accepted tampered content", algorithm)
+		}
+	}
+
+	otherKey := minisign(t, "ED", priv, []byte{8, 7, 6, 5, 4, 3, 2, 1}, content)
+	if err := Verify(Minisign, key, bytes.NewReader(content), otherKey); err == nil {
+		t.Errorf("Verify() accepted signature with another key id")
+	}
+}
@@ -3,12 +3,12 @@ package generic
 import (
 	"context"
 	"fmt"
-	"io/ioutil"
 
 	"github.com/blang/semver"
 	"github.com/pkg/errors"
 
 	"github.com/gofish-bot/gofish-bot/gofishgithub"
+	"github.com/gofish-bot/gofish-bot/lint"
 	"github.com/gofish-bot/gofish-bot/log"
 	"github.com/gofish-bot/gofish-bot/models"
 	"github.com/gofish-bot/gofish-bot/printer"
@@ -19,7 +19,8 @@ import (
 )
 
 type Generic struct {
-	GoFish *gofishgithub.GoFish
+	GoFish   *gofishgithub.GoFish
+	Settings models.Settings
 }
 
 func (g *Generic) UpdateApplications(ctx context.Context, appsGithub []models.DesiredApp, createPullrequests bool) {
@@ -30,23 +31,17 @@ func (g *Generic) UpdateApplications(ctx context.Context, appsGithub []models.De
 		if err != nil {
 			log.G(ctx).Warnf("Error in handling %s: %v", app.Name, err)
 		} else {
-			currentVersion, err := g.GoFish.GetCurrentVersion(ctx, app)
-			if err != nil {
-				log.G(ctx).Warn(errors.Wrap(err, "Could not find current version"))
-			} else {
-				application.CurrentVersion = currentVersion
-			}
-
 			applications = append(applications, application)
 		}
 	}
 
 	printer.Table(applications)
 
+	ready := []*models.Application{}
 	for _, app := range applications {
 		if app.CurrentVersion != app.Version {
 
-			checksumService := NewChecksumService(*app, g.GoFish.Client)
+			checksumService := NewChecksumService(*app, g.GoFish.HTTPClient, g.GoFish.Cache)
 			content, err := g.getUpgradedFood(ctx, app, checksumService)
 			if err != nil {
 				log.G(ctx).Infof("Cound not upgrade current food: %s %s", app.Name, err)
@@ -57,17 +52,17 @@ func (g *Generic) UpdateApplications(ctx context.Context, appsGithub []models.De
 			upgradeToBeta := (!strings.Contains(app.CurrentVersion, "beta")) && strings.Contains(app.Version, "beta")
 
 			if needsUpgrade {
-				g.CreateLuaFile(ctx, app, content)
-				err = g.GoFish.LintString(app.Name, content)
+				err = g.CreateLuaFile(ctx, app, content)
 				if err != nil {
-
-					// Allow generic packages to have fewer packages
-					if strings.Contains(err.Error(), "Bad number of packages") {
-						log.G(ctx).Infof("Linting failed, but continuing: %v", err)
-					} else {
-						log.G(ctx).Warnf("Linting failed: %v", err)
-						continue
-					}
+					log.G(ctx).Warnf("Could not write food: %v", err)
+					continue
+				}
+				app.LintReport = g.GoFish.LintString(app.Name, content, app.LintRules)
+				if app.LintReport.Failed() {
+					log.G(ctx).Warnf("Linting failed: %s", app.LintReport)
+					continue
+				} else if len(app.LintReport.Findings) > 0 {
+					log.G(ctx).Infof("Linting found warnings, but continuing: %s", app.LintReport)
 				} else {
 					log.G(ctx).Infof("Linting ok: %v", app.Name)
 				}
@@ -77,19 +72,28 @@ func (g *Generic) UpdateApplications(ctx context.Context, appsGithub []models.De
 				log.G(ctx).Infof("Will not upgrade to beta release: %s", app.Name)
 			} else if needsUpgrade && createPullrequests {
 				log.G(ctx).Infof("Creating pr for release: %s", app.Name)
-				g.CreateLuaFile(ctx, app, content)
-				g.CreatePullRequest(ctx, app, content)
+				ready = append(ready, app)
 			} else if missing {
 				log.G(ctx).Infof("Generic strategy can not create new apps: %s", app.Name)
 			}
 		}
 	}
+
+	g.GoFish.CreatePullRequests(ctx, ready, g.Settings.Batch)
 }
 
 func (g *Generic) CreateApplication(ctx context.Context, app models.DesiredApp) (*models.Application, error) {
 	log.G(ctx).Infof("## Creating Application for %s: https://github.com/%s/%s/releases/", app.Name, app.Org, app.Repo)
 
-	releaseList, _, err := g.GoFish.Client.Repositories.ListReleases(ctx, app.Org, app.Repo, &ghApi.ListOptions{})
+	// The releases are listed back to the current version for the changelog
+	currentVersion, err := g.GoFish.GetCurrentVersion(ctx, app)
+	if err != nil {
+		log.G(ctx).Warn(errors.Wrap(err, "Could not find current version"))
+	}
+	version := func(tag string) string {
+		return strings.Replace(getVersion(tag, app.Name), "v", "", 1)
+	}
+	releaseList, err := g.GoFish.ListReleases(ctx, app.Org, app.Repo, currentVersion, version)
 	if err != nil {
 		return nil, err
 	}
@@ -126,35 +130,49 @@ func (g *Generic) CreateApplication(ctx context.Context, app models.DesiredApp)
 		ReleaseName:        releaseName,
 		ReleaseDescription: release.GetBody(),
 		ReleaseLink:        release.GetHTMLURL(),
+		ReleaseNotes:       gofishgithub.ReleaseNotes(releaseList, version),
+		CurrentVersion:     currentVersion,
 		Name:               app.Name,
 		Repo:               app.Repo,
 		Description:        repoDetails.GetDescription(),
 		Organization:       app.Org,
-		Version:            strings.Replace(getVersion(release.GetTagName(), app.Name), "v", "", 1),
+		Version:            version(release.GetTagName()),
 		Arch:               app.Arch,
 		Licence:            repoDetails.GetLicense().GetSPDXID(),
 		Homepage:           homepage,
+		Label:              app.Label,
 		Assets:             []models.Asset{},
 	}
 
+	application.LintRules, err = lintRules(g.Settings, app)
+	if err != nil {
+		return nil, err
+	}
+
 	return &application, nil
 }
 
-func (g *Generic) CreateLuaFile(ctx context.Context, application *models.Application, content string) {
-	err := ioutil.WriteFile("/usr/local/gofish/tmp/github.com/fmotrifork/fish-food/Food/"+application.Name+".lua", []byte(content), 0644)
+// lintRules resolves the lint rules of the app. Generic foods keep the
+// packages of the current food, so fewer packages than usual is only a warning.
+func lintRules(settings models.Settings, app models.DesiredApp) (lint.Rules, error) {
+	rules, err := lint.DefaultRules().Apply(&lint.Config{Warn: []string{lint.RuleMinPackages}})
+	if err != nil {
+		return rules, err
+	}
+	rules, err = rules.Apply(&settings.Lint)
 	if err != nil {
-		log.G(ctx).Warn(err)
+		return rules, err
 	}
+	return rules.Apply(app.Lint)
 }
 
-func (g *Generic) CreatePullRequest(ctx context.Context, application *models.Application, content string) {
+// CreateLuaFile writes the upgraded food of the application to the workspace
+func (g *Generic) CreateLuaFile(ctx context.Context, application *models.Application, content string) error {
+	return g.GoFish.Workspace.WriteFood(application.Name, []byte(content))
+}
 
-	log.G(ctx).Infof("## Creating Pullrequest for %s version %s", application.Name, application.Version)
-	err := g.GoFish.CreatePullRequest(ctx, application, []byte(content))
-	if err != nil {
-		log.G(ctx).Warnf("Failed creating PR: %v", err)
-		return
-	}
+func (g *Generic) CreatePullRequest(ctx context.Context, application *models.Application) {
+	g.GoFish.CreatePullRequests(ctx, []*models.Application{application}, models.BatchSettings{})
 }
 
 func findRelease(app models.DesiredApp, releaseList []*ghApi.RepositoryRelease, tagList []*ghApi.RepositoryTag) *ghApi.RepositoryRelease {
@@ -245,6 +263,13 @@ func (g *Generic) getUpgradedFood(ctx context.Context, app *models.Application,
 
 		log.G(ctx).Debugf("Replacing old sha %s with %s", foodPackage.SHA256, newSha)
 		versionUpgradedFoodStr = strings.ReplaceAll(versionUpgradedFoodStr, foodPackage.SHA256, newSha)
+
+		app.Assets = append(app.Assets, models.Asset{
+			Os:             foodPackage.OS,
+			Arch:           foodPackage.Arch,
+			Sha256:         newSha,
+			ChecksumSource: models.ChecksumComputed,
+		})
 	}
 	return versionUpgradedFoodStr, nil
 }
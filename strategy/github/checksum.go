@@ -1,14 +1,17 @@
 package github
 
 import (
-	"crypto/sha256"
+	"context"
+	"crypto/sha512"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"net/http"
 	"os"
-	"strings"
 
+	"github.com/gofish-bot/gofish-bot/cache"
+	"github.com/gofish-bot/gofish-bot/checksum"
+	"github.com/gofish-bot/gofish-bot/download"
 	"github.com/gofish-bot/gofish-bot/log"
 	"github.com/gofish-bot/gofish-bot/models"
 	ghApi "github.com/google/go-github/v32/github"
@@ -16,150 +19,178 @@ import (
 
 type ChecksumService struct {
 	application models.Application
-	checksums   []Checksum
+	checksums   []checksum.Entry
+	assets      []*ghApi.ReleaseAsset
 	preloaded   bool
-	ghClient    *ghApi.Client
+	verify      bool
+	httpClient  *http.Client
+	cache       *cache.Cache
 }
 
-type Checksum struct {
-	AssetName string
-	SHA       string
-}
-
-func NewChecksumService(application models.Application, ghClient *ghApi.Client, assets []*ghApi.ReleaseAsset) *ChecksumService {
+func NewChecksumService(application models.Application, httpClient *http.Client, downloadCache *cache.Cache, assets []*ghApi.ReleaseAsset, verify bool) (*ChecksumService, error) {
 	c := &ChecksumService{
 		application: application,
-		ghClient:    ghClient,
+		assets:      assets,
+		httpClient:  httpClient,
+		cache:       downloadCache,
+		verify:      verify,
+	}
+
+	// Every asset must be downloaded to have its signature verified
+	if c.signedAssets() {
+		c.verify = true
+	}
+
+	err := c.preLoadFromAssets(assets)
+	if err != nil {
+		return nil, err
 	}
-	c.preLoadFromAssets(assets)
-	return c
+	return c, nil
 
 }
 
-func (c *ChecksumService) getChecksum(url, assetName string) string {
+// getChecksum returns the sha256 of the asset and where it came from. A
+// published sha256 is trusted unless verification is enabled, in which case
+// the asset is downloaded and compared to the published sha256 and sha512.
+func (c *ChecksumService) getChecksum(url, assetName string) (string, string, error) {
 
-	for _, checksum := range c.checksums {
-		if strings.Contains(checksum.AssetName, assetName) {
-			log.L.Debugf("Found sha %s for %s in %s\n", checksum.SHA, assetName, checksum.AssetName)
-			return checksum.SHA
-		}
+	published256, has256 := checksum.Lookup(c.checksums, assetName, checksum.SHA256)
+	published512, has512 := checksum.Lookup(c.checksums, assetName, checksum.SHA512)
+
+	// A computed checksum can not be trusted when the checksums must be signed
+	if c.signedChecksums() && !has256 && !has512 {
+		return "", "", fmt.Errorf("no signed checksum found for %s", assetName)
+	}
+
+	if has256 && !c.verify {
+		log.L.Debugf("Found sha %s for %s\n", published256, assetName)
+		return published256, models.ChecksumPublished, nil
 	}
-	log.L.Debugf("Falling back to calculating SHA for %s using %s\n", assetName, url)
-	sha, err := c.getShaFromURL(assetName, url)
+
+	log.L.Debugf("Calculating SHA for %s using %s\n", assetName, url)
+	sha256sum, sha512sum, err := c.getShaFromURL(url, has512)
 	if err != nil {
-		log.L.Error(err)
-		return ""
+		return "", "", err
+	}
+
+	if c.signedAssets() {
+		err = c.verifySignature(assetName, url)
+		if err != nil {
+			return "", "", err
+		}
+	}
+
+	if has256 && published256 != sha256sum {
+		return "", "", fmt.Errorf("sha256 mismatch for %s: published %s, downloaded %s", assetName, published256, sha256sum)
+	}
+	if has512 && published512 != sha512sum {
+		return "", "", fmt.Errorf("sha512 mismatch for %s: published %s, downloaded %s", assetName, published512, sha512sum)
+	}
+	if has256 || has512 {
+		log.L.Debugf("Verified published checksum for %s", assetName)
+		return sha256sum, models.ChecksumVerified, nil
 	}
 
-	return sha
+	return sha256sum, models.ChecksumComputed, nil
 }
 
-func (c *ChecksumService) getShaFromURL(assetName, assetURL string) (string, error) {
-	content, err := c.downloadFile(assetName, assetURL)
+// getShaFromURL returns the sha256 of the asset, computed while it was
+// downloaded, and its sha512 when withSha512 is set
+func (c *ChecksumService) getShaFromURL(assetURL string, withSha512 bool) (string, string, error) {
+	entry, err := c.download(assetURL)
 	if err != nil {
-		return "", fmt.Errorf("error while downloading package to calculate shasum: %v", err)
+		return "", "", fmt.Errorf("error while downloading package to calculate shasum: %w", err)
+	}
+	defer entry.Close()
+	if !withSha512 {
+		return entry.SHA256, "", nil
+	}
+
+	content, err := c.cache.Open(entry)
+	if err != nil {
+		return "", "", err
 	}
 	defer content.Close()
 
-	h := sha256.New()
-	if _, err := io.Copy(h, content); err != nil {
-		return "", fmt.Errorf("error while calculating shasum of package: %v", err)
+	h512 := sha512.New()
+	if _, err := io.Copy(h512, content); err != nil {
+		return "", "", fmt.Errorf("error while calculating shasum of package: %v", err)
 	}
-	return fmt.Sprintf("%x", h.Sum(nil)), nil
+	return entry.SHA256, fmt.Sprintf("%x", h512.Sum(nil)), nil
 }
 
-func (c *ChecksumService) preLoadFromAssets(assets []*ghApi.ReleaseAsset) {
-	checksums := ""
+func (c *ChecksumService) preLoadFromAssets(assets []*ghApi.ReleaseAsset) error {
+	cs := []checksum.Entry{}
+	verified := 0
 
 	for _, asset := range assets {
-		if strings.Contains(asset.GetName(), "checksums") && !strings.Contains(asset.GetName(), "512") {
-			reader, err := c.downloadFile(asset.GetName(), asset.GetBrowserDownloadURL())
-			if err != nil {
-				log.L.Errorf("Could not download checksums: %s %v", asset.GetBrowserDownloadURL(), err)
-			}
-			defer reader.Close()
-			checksumBytes, err := ioutil.ReadAll(reader)
-			if err != nil {
-				log.L.Errorf("Could not download checksums: %v", err)
-			}
-			checksums = string(checksumBytes)
-
+		if !checksum.IsChecksumFile(asset.GetName()) {
+			continue
 		}
-		if strings.Contains(asset.GetName(), "sha256") {
-			csReader, err := c.downloadFile(asset.GetName(), asset.GetBrowserDownloadURL())
-			if err != nil {
-				log.L.Errorf("Could not download checksums: %v", csReader)
-			}
-			csBytes, err := ioutil.ReadAll(csReader)
-			if err != nil {
-				log.L.Errorf("Could not download checksums: %v", err)
-			}
-			csStr := string(csBytes)
-
-			// csStr may be either "sha assetname" or just "sha"
-			// - If we postfix with assetname then the second element will always contain the assetname
-			checksums += fmt.Sprintf("%s %s\n", strings.Trim(csStr, "\n"), strings.ReplaceAll(asset.GetName(), ".sha256", ""))
+		// Unsigned checksums files, like sidecars next to a signed checksums
+		// file, can not be trusted when the checksums must be signed
+		if c.signedChecksums() && c.findSignature(asset.GetName()) == nil {
+			log.L.Debugf("Skipping unsigned checksums file %s", asset.GetName())
+			continue
 		}
-	}
-	cs := []Checksum{}
-
-	for _, line := range strings.Split(strings.TrimSuffix(checksums, "\n"), "\n") {
-		if line == "" {
+		reader, err := c.downloadFile(asset.GetBrowserDownloadURL())
+		if err != nil {
+			log.L.Errorf("Could not download checksums: %s %v", asset.GetBrowserDownloadURL(), err)
 			continue
 		}
-		x := strings.Fields(strings.TrimSpace(line))
-		log.L.Debugf("SHA line: %s len: %d", x, len(x))
-
-		if len(x) < 2 {
+		checksumBytes, err := ioutil.ReadAll(reader)
+		reader.Close()
+		if err != nil {
+			log.L.Errorf("Could not download checksums: %v", err)
 			continue
 		}
-		cs = append(cs, Checksum{
-			SHA:       x[0],
-			AssetName: x[1],
-		})
-	}
-	c.checksums = cs
-}
 
-func (c *ChecksumService) downloadFile(assetName, url string) (io.ReadCloser, error) {
-
-	path := fmt.Sprintf("/tmp/gofish-bot/%s-%s-%s-%s", c.application.Organization, c.application.Name, c.application.ReleaseName, assetName)
+		if c.signedChecksums() {
+			err = c.verifySignature(asset.GetName(), asset.GetBrowserDownloadURL())
+			if err != nil {
+				return err
+			}
+			verified++
+		}
 
-	if _, err := os.Stat(path); err == nil {
-		log.L.Debugf("Getting from cache: %s", url)
-		return getFile(path)
+		entries := checksum.Parse(asset.GetName(), checksumBytes)
+		log.L.Debugf("Found %d checksums in %s", len(entries), asset.GetName())
+		cs = append(cs, entries...)
 	}
 
-	log.L.Debugf("Downloading: %s to %s", url, path)
-	req, err := c.ghClient.NewRequest(http.MethodGet, url, nil)
-	if err != nil {
-		return nil, err
+	if c.signedChecksums() && verified == 0 {
+		return fmt.Errorf("no checksums file to verify the signature of for %s", c.application.Name)
 	}
 
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
+	c.checksums = cs
+	return nil
+}
 
-	out, err := os.Create(path)
+func (c *ChecksumService) downloadFile(url string) (io.ReadCloser, error) {
+	entry, err := c.download(url)
 	if err != nil {
 		return nil, err
 	}
-	defer out.Close()
-
-	_, err = io.Copy(out, resp.Body)
+	f, err := c.cache.Open(entry)
 	if err != nil {
+		entry.Close()
 		return nil, err
 	}
-	return getFile(path)
+	return &entryReader{File: f, entry: entry}, nil
 }
 
-func getFile(path string) (io.ReadCloser, error) {
-	f, err := os.Open(path)
-	if err != nil {
-		return nil, err
-	}
+// entryReader reads the cached file of an entry and releases the entry when
+// it is closed
+type entryReader struct {
+	*os.File
+	entry *cache.Entry
+}
+
+func (r *entryReader) Close() error {
+	defer r.entry.Close()
+	return r.File.Close()
+}
 
-	return f, nil
+func (c *ChecksumService) download(url string) (*cache.Entry, error) {
+	return download.Get(context.Background(), c.httpClient, c.cache, url)
 }
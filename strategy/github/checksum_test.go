@@ -0,0 +1,120 @@
+package github
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	ghApi "github.com/google/go-github/v32/github"
+
+	"github.com/gofish-bot/gofish-bot/cache"
+	"github.com/gofish-bot/gofish-bot/models"
+)
+
+func TestChecksumService_signedChecksums(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gofish-bot-checksum")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(&priv.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyPath := filepath.Join(dir, "cosign.pub")
+	err = ioutil.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	linuxSha := fmt.Sprintf("%x", sha256.Sum256([]byte("linux")))
+	darwinSha := fmt.Sprintf("%x", sha256.Sum256([]byte("darwin")))
+	checksums := []byte(linuxSha + "  tool_1.0.0_linux_amd64.tar.gz\n" + darwinSha + "  tool_1.0.0_darwin_amd64.tar.gz\n")
+	digest := sha256.Sum256(checksums)
+	rawSig, err := ecdsa.SignASN1(rand.Reader, priv, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A goreleaser release signed with cosign, with an unsigned sidecar
+	files := map[string][]byte{
+		"checksums.txt":                       checksums,
+		"checksums.txt.sig":                   []byte(base64.StdEncoding.EncodeToString(rawSig)),
+		"checksums.txt.pem":                   []byte("-----BEGIN CERTIFICATE-----\n-----END CERTIFICATE-----\n"),
+		"tool_1.0.0_linux_amd64.tar.gz":       []byte("linux"),
+		"tool_1.0.0_darwin_amd64.tar.gz":      []byte("darwin"),
+		"tool_1.0.0_windows_amd64.zip":        []byte("windows"),
+		"tool_1.0.0_windows_amd64.zip.sha256": []byte(fmt.Sprintf("%x", sha256.Sum256([]byte("windows")))),
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		content, ok := files[filepath.Base(r.URL.Path)]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(content)
+	}))
+	defer server.Close()
+
+	assets := []*ghApi.ReleaseAsset{}
+	for name := range files {
+		assets = append(assets, &ghApi.ReleaseAsset{
+			Name:               ghApi.String(name),
+			BrowserDownloadURL: ghApi.String(server.URL + "/" + name),
+		})
+	}
+
+	c, err := cache.New(filepath.Join(dir, "cache"), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	app := models.Application{
+		Name:         "tool",
+		Verification: &models.Verification{Method: "cosign", Key: keyPath},
+	}
+	service, err := NewChecksumService(app, server.Client(), c, assets, false)
+	if err != nil {
+		t.Fatalf("NewChecksumService() error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		asset   string
+		wantSha string
+		wantErr bool
+	}{
+		{name: "signed", asset: "tool_1.0.0_linux_amd64.tar.gz", wantSha: linuxSha},
+		{name: "signed darwin", asset: "tool_1.0.0_darwin_amd64.tar.gz", wantSha: darwinSha},
+		{name: "only in unsigned sidecar", asset: "tool_1.0.0_windows_amd64.zip", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sha, source, err := service.getChecksum(server.URL+"/"+tt.asset, tt.asset)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("getChecksum() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if sha != tt.wantSha || source != models.ChecksumPublished {
+				t.Errorf("getChecksum() = %s, %s, want %s, %s", sha, source, tt.wantSha, models.ChecksumPublished)
+			}
+		})
+	}
+}
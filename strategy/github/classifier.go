@@ -0,0 +1,59 @@
+package github
+
+import (
+	"strings"
+
+	"github.com/google/go-github/v32/github"
+)
+
+// classifyOs returns the os of a release asset based on its lower case name,
+// or an empty string if the asset is not for the configured arch
+func classifyOs(cleanName, arch string) string {
+	if !strings.Contains(cleanName, arch) {
+		return ""
+	}
+	if strings.Contains(cleanName, "osx") || strings.Contains(cleanName, "darwin") || strings.Contains(cleanName, "macos") || strings.Contains(cleanName, "mac") {
+		return "darwin"
+	}
+	if strings.Contains(cleanName, "linux") || strings.Contains(cleanName, "ubuntu") {
+		return "linux"
+	}
+	if strings.Contains(cleanName, "win") || strings.Contains(cleanName, "windows") {
+		return "windows"
+	}
+	return ""
+}
+
+// preferenceRank ranks an asset name by the first fragment in prefer it
+// contains. Lower is better, names containing a fragment from avoid are
+// ranked below everything else.
+func preferenceRank(cleanName string, prefer, avoid []string) int {
+	rank := len(prefer)
+	for i, p := range prefer {
+		if strings.Contains(cleanName, strings.ToLower(p)) {
+			rank = i
+			break
+		}
+	}
+	for _, a := range avoid {
+		if strings.Contains(cleanName, strings.ToLower(a)) {
+			return rank + len(prefer) + 1
+		}
+	}
+	return rank
+}
+
+// selectAsset chooses the preferred asset among multiple candidates for the
+// same os/arch. Candidates with the same rank keep their release order.
+func selectAsset(candidates []*github.ReleaseAsset, prefer, avoid []string) *github.ReleaseAsset {
+	var best *github.ReleaseAsset
+	bestRank := 0
+	for _, candidate := range candidates {
+		rank := preferenceRank(strings.ToLower(candidate.GetName()), prefer, avoid)
+		if best == nil || rank < bestRank {
+			best = candidate
+			bestRank = rank
+		}
+	}
+	return best
+}
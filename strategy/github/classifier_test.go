@@ -0,0 +1,89 @@
+package github
+
+import (
+	"testing"
+
+	"github.com/google/go-github/v32/github"
+)
+
+func Test_classifyOs(t *testing.T) {
+	tests := []struct {
+		name string
+		arch string
+		want string
+	}{
+		{name: "k9s_darwin_x86_64.tar.gz", arch: "x86_64", want: "darwin"},
+		{name: "k9s_linux_x86_64.tar.gz", arch: "x86_64", want: "linux"},
+		{name: "k9s_windows_x86_64.tar.gz", arch: "x86_64", want: "windows"},
+		{name: "k9s_linux_arm64.tar.gz", arch: "x86_64", want: ""},
+		{name: "kind-macos-amd64", arch: "amd64", want: "darwin"},
+		{name: "kind-freebsd-amd64", arch: "amd64", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := classifyOs(tt.name, tt.arch); got != tt.want {
+				t.Errorf("classifyOs() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_selectAsset(t *testing.T) {
+	prefer := []string{"static", "musl", "gnu"}
+	avoid := []string{"-debug", "-symbols"}
+
+	tests := []struct {
+		name       string
+		candidates []string
+		prefer     []string
+		want       string
+	}{
+		{
+			name:       "single candidate",
+			candidates: []string{"tool-linux-amd64"},
+			prefer:     prefer,
+			want:       "tool-linux-amd64",
+		},
+		{
+			name:       "prefers static over musl",
+			candidates: []string{"tool-linux-amd64", "tool-linux-amd64-musl", "tool-linux-amd64-static"},
+			prefer:     prefer,
+			want:       "tool-linux-amd64-static",
+		},
+		{
+			name:       "prefers musl over gnu",
+			candidates: []string{"tool-x86_64-unknown-linux-gnu.tar.gz", "tool-x86_64-unknown-linux-musl.tar.gz"},
+			prefer:     prefer,
+			want:       "tool-x86_64-unknown-linux-musl.tar.gz",
+		},
+		{
+			name:       "avoids debug builds",
+			candidates: []string{"tool-linux-amd64-static-debug", "tool-linux-amd64"},
+			prefer:     prefer,
+			want:       "tool-linux-amd64",
+		},
+		{
+			name:       "keeps release order without preferences",
+			candidates: []string{"tool-linux-amd64-slim", "tool-linux-amd64"},
+			prefer:     nil,
+			want:       "tool-linux-amd64-slim",
+		},
+		{
+			name:       "avoided asset is used as last resort",
+			candidates: []string{"tool-linux-amd64-symbols"},
+			prefer:     prefer,
+			want:       "tool-linux-amd64-symbols",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			candidates := []*github.ReleaseAsset{}
+			for _, name := range tt.candidates {
+				candidates = append(candidates, &github.ReleaseAsset{Name: github.String(name)})
+			}
+			if got := selectAsset(candidates, tt.prefer, avoid); got.GetName() != tt.want {
+				t.Errorf("selectAsset() = %v, want %v", got.GetName(), tt.want)
+			}
+		})
+	}
+}
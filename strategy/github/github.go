@@ -1,16 +1,17 @@
 package github
 
 import (
-	"bufio"
 	"bytes"
 	"context"
-	"os"
 	"sort"
 
 	"github.com/blang/semver"
 	"github.com/pkg/errors"
 
+	"github.com/gofish-bot/gofish-bot/checksum"
+	"github.com/gofish-bot/gofish-bot/download"
 	"github.com/gofish-bot/gofish-bot/gofishgithub"
+	"github.com/gofish-bot/gofish-bot/lint"
 	"github.com/gofish-bot/gofish-bot/log"
 	"github.com/gofish-bot/gofish-bot/models"
 	"github.com/gofish-bot/gofish-bot/printer"
@@ -22,7 +23,8 @@ import (
 )
 
 type Github struct {
-	GoFish *gofishgithub.GoFish
+	GoFish   *gofishgithub.GoFish
+	Settings models.Settings
 }
 
 func (g *Github) UpdateApplications(ctx context.Context, appsGithub []models.DesiredApp, createPullrequests bool) {
@@ -33,19 +35,13 @@ func (g *Github) UpdateApplications(ctx context.Context, appsGithub []models.Des
 		if err != nil {
 			log.G(ctx).Warnf("Error in handling %s: %v", app.Name, err)
 		} else {
-			currentVersion, err := g.GoFish.GetCurrentVersion(ctx, app)
-			if err != nil {
-				log.G(ctx).Warn(errors.Wrap(err, "Could not find current version"))
-			} else {
-				application.CurrentVersion = currentVersion
-			}
-
 			applications = append(applications, application)
 		}
 	}
 
 	printer.Table(applications)
 
+	ready := []*models.Application{}
 	for _, app := range applications {
 		if app.CurrentVersion != app.Version {
 			missing := app.CurrentVersion == ""
@@ -53,34 +49,50 @@ func (g *Github) UpdateApplications(ctx context.Context, appsGithub []models.Des
 			upgradeToBeta := (!strings.Contains(app.CurrentVersion, "beta")) && strings.Contains(app.Version, "beta")
 
 			if needsUpgrade {
-				g.CreateLuaFile(ctx, app)
+				err := g.CreateLuaFile(ctx, app)
+				if err != nil {
+					log.G(ctx).Warnf("Could not write food: %v", err)
+					continue
+				}
 
-				err := g.GoFish.Lint(app)
+				app.LintReport, err = g.GoFish.Lint(app)
 				if err != nil {
 					log.G(ctx).Warnf("Linting failed: '%v'", err)
 					continue
-				} else {
-					log.G(ctx).Infof("Linting ok: %v", app.Name)
 				}
+				if app.LintReport.Failed() {
+					log.G(ctx).Warnf("Linting failed: %s", app.LintReport)
+					continue
+				}
+				log.G(ctx).Infof("Linting ok: %v", app.Name)
 			}
 
 			if upgradeToBeta {
 				log.G(ctx).Infof("Will not upgrade to beta release: %s", app.Name)
 			} else if needsUpgrade && createPullrequests {
 				log.G(ctx).Infof("Creating pr for release: %s", app.Name)
-				g.CreateLuaFile(ctx, app)
-				g.CreatePullRequest(ctx, app)
+				ready = append(ready, app)
 			} else if missing {
 				log.G(ctx).Infof("Will not create new apps for now: %s", app.Name)
 			}
 		}
 	}
+
+	g.GoFish.CreatePullRequests(ctx, ready, g.Settings.Batch)
 }
 
 func (g *Github) CreateApplication(ctx context.Context, app models.DesiredApp) (*models.Application, error) {
 	log.G(ctx).Infof("## Creating Application for %s: https://github.com/%s/%s/releases/", app.Name, app.Org, app.Repo)
 
-	releaseList, _, err := g.GoFish.Client.Repositories.ListReleases(ctx, app.Org, app.Repo, &ghApi.ListOptions{})
+	// The releases are listed back to the current version for the changelog
+	currentVersion, err := g.GoFish.GetCurrentVersion(ctx, app)
+	if err != nil {
+		log.G(ctx).Warn(errors.Wrap(err, "Could not find current version"))
+	}
+	version := func(tag string) string {
+		return strings.Replace(strings.Replace(tag, "v", "", 1), app.Name+"-", "", 1)
+	}
+	releaseList, err := g.GoFish.ListReleases(ctx, app.Org, app.Repo, currentVersion, version)
 	if err != nil {
 		return nil, err
 	}
@@ -102,6 +114,9 @@ func (g *Github) CreateApplication(ctx context.Context, app models.DesiredApp) (
 	var application = models.Application{
 		ReleaseName:        releaseName,
 		ReleaseDescription: release.GetBody(),
+		ReleaseLink:        release.GetHTMLURL(),
+		ReleaseNotes:       gofishgithub.ReleaseNotes(releaseList, version),
+		CurrentVersion:     currentVersion,
 		Name:               app.Name,
 		Repo:               app.Repo,
 		Description:        repoDetails.GetDescription(),
@@ -109,48 +124,65 @@ func (g *Github) CreateApplication(ctx context.Context, app models.DesiredApp) (
 		Path:               app.Path,
 		Version:            strings.Replace(releaseName, "v", "", 1),
 		Arch:               app.Arch,
+		URL:                app.URL,
+		Prefer:             g.Settings.Prefer,
+		Avoid:              g.Settings.Avoid,
+		Verification:       app.Verify,
+		SmokeTest:          g.Settings.SmokeTest,
+		SmokeArgs:          app.SmokeArgs,
+		Label:              app.Label,
 		Licence:            repoDetails.GetLicense().GetSPDXID(),
 		Homepage:           homepage,
 		Assets:             []models.Asset{},
 	}
 
-	checksumService := NewChecksumService(application, g.GoFish.Client, release.Assets)
-	application.Assets = g.GetAssets(ctx, application, release.Assets, checksumService)
-	return &application, nil
-}
+	application.LintRules, err = lint.Resolve(g.Settings.Lint, app.Lint)
+	if err != nil {
+		return nil, err
+	}
+	if len(app.Prefer) > 0 {
+		application.Prefer = app.Prefer
+	}
+	if len(app.Avoid) > 0 {
+		application.Avoid = app.Avoid
+	}
 
-func (g *Github) CreateLuaFile(ctx context.Context, application *models.Application) {
-	f, err := os.Create("/usr/local/gofish/tmp/github.com/fmotrifork/fish-food/Food/" + application.Name + ".lua")
+	if application.URL != "" {
+		log.G(ctx).Debugf("Resolving assets from url template: %s", application.URL)
+		checksumService, err := NewChecksumService(application, g.GoFish.HTTPClient, g.GoFish.Cache, nil, g.Settings.Verify)
+		if err != nil {
+			return nil, err
+		}
+		application.Assets, err = g.GetTemplatedAssets(application, checksumService)
+		if err != nil {
+			return nil, err
+		}
+		return &application, nil
+	}
+
+	checksumService, err := NewChecksumService(application, g.GoFish.HTTPClient, g.GoFish.Cache, release.Assets, g.Settings.Verify)
 	if err != nil {
-		log.G(ctx).Warn(err)
-		return
+		return nil, err
 	}
-	defer f.Close()
-	err = serializeLuaContent(application, f)
+	application.Assets, err = g.GetAssets(ctx, application, release.Assets, checksumService)
 	if err != nil {
-		log.G(ctx).Warn(err)
-		return
+		return nil, err
 	}
+	return &application, nil
 }
 
-func (g *Github) CreatePullRequest(ctx context.Context, application *models.Application) {
-
-	log.G(ctx).Infof("## Creating Pullrequest for %s version %s", application.Name, application.Version)
-
+// CreateLuaFile renders the food of the application into the workspace
+func (g *Github) CreateLuaFile(ctx context.Context, application *models.Application) error {
 	var b bytes.Buffer
-	foo := bufio.NewWriter(&b)
-	err := serializeLuaContent(application, foo)
+	err := serializeLuaContent(application, &b)
 	if err != nil {
-		log.G(ctx).Warn(err)
-		return
+		return err
 	}
-	foo.Flush()
+	return g.GoFish.Workspace.WriteFood(application.Name, b.Bytes())
+}
 
-	err = g.GoFish.CreatePullRequest(ctx, application, b.Bytes())
-	if err != nil {
-		log.G(ctx).Warnf("Failed creating PR: %v", err)
-		return
-	}
+func (g *Github) CreatePullRequest(ctx context.Context, application *models.Application) {
+	g.GoFish.CreatePullRequests(ctx, []*models.Application{application}, models.BatchSettings{})
 }
 
 func findRelease(app models.DesiredApp, releaseList []*ghApi.RepositoryRelease) *ghApi.RepositoryRelease {
@@ -179,13 +211,15 @@ func findRelease(app models.DesiredApp, releaseList []*ghApi.RepositoryRelease)
 	return release
 }
 
-func (g *Github) GetAssets(ctx context.Context, app models.Application, releaseAssets []*github.ReleaseAsset, checksumService *ChecksumService) []models.Asset {
+func (g *Github) GetAssets(ctx context.Context, app models.Application, releaseAssets []*github.ReleaseAsset, checksumService *ChecksumService) ([]models.Asset, error) {
 
-	assets := []models.Asset{}
+	candidates := map[string][]*github.ReleaseAsset{}
+	universal := []*github.ReleaseAsset{}
+	darwinArm64 := false
 
 	for _, releaseAsset := range releaseAssets {
 		log.G(ctx).Debugf("Asset: %s ", *releaseAsset.Name)
-		if strings.Contains(releaseAsset.GetName(), "sha256") || strings.Contains(releaseAsset.GetName(), "sha512") {
+		if checksum.IsChecksumFile(releaseAsset.GetName()) || strings.Contains(releaseAsset.GetName(), "sha256") || strings.Contains(releaseAsset.GetName(), "sha512") {
 			continue
 		}
 		cleanName := strings.ToLower(releaseAsset.GetName())
@@ -199,70 +233,122 @@ func (g *Github) GetAssets(ctx context.Context, app models.Application, releaseA
 			strings.HasSuffix(cleanName, ".yaml") ||
 			strings.HasSuffix(cleanName, ".txt") ||
 			strings.HasSuffix(cleanName, ".sig") ||
+			strings.HasSuffix(cleanName, ".asc") ||
+			strings.HasSuffix(cleanName, ".pem") ||
+			strings.HasSuffix(cleanName, ".minisig") ||
 			strings.HasSuffix(cleanName, ".dmg") {
 			continue
 		}
 
-		assetName := strings.Replace(releaseAsset.GetName(), app.Name, "\" .. name .. \"", 1)
-		assetName = strings.Replace(assetName, app.Version, "\" .. version .. \"", 1)
-		path := "name"
-		if !strings.Contains(cleanName, "tar") && !strings.Contains(cleanName, "zip") {
-			path = strings.Replace(releaseAsset.GetName(), app.Name, "name .. \"", 1) + "\""
-			path = strings.Replace(path, app.Version, "\" .. version .. \"", 1)
-		}
-
 		log.G(ctx).Debugf("Clean asset name: %s ", cleanName)
 
-		if (strings.Contains(cleanName, "osx") || strings.Contains(cleanName, "darwin") || strings.Contains(cleanName, "macos") || strings.Contains(cleanName, "mac")) && strings.Contains(cleanName, app.Arch) {
-			log.G(ctx).Debugf(" - OSX asset %s ", *releaseAsset.Name)
-			assets = append(assets, models.Asset{
-				Arch:        "amd64",
-				Os:          "darwin",
-				AssertName:  assetName,
-				InstallPath: "\"bin/\" .. name",
-				Path:        path,
-				Sha256:      checksumService.getChecksum(releaseAsset.GetBrowserDownloadURL(), releaseAsset.GetName()),
-				Executable:  true,
-			})
-
-		} else if (strings.Contains(cleanName, "linux") || strings.Contains(cleanName, "ubuntu")) && strings.Contains(cleanName, app.Arch) {
-
-			log.G(ctx).Debugf(" - linux asset %s ", *releaseAsset.Name)
-			assets = append(assets, models.Asset{
-				Arch:        "amd64",
-				Os:          "linux",
-				AssertName:  assetName,
-				InstallPath: "\"bin/\" .. name",
-				Path:        path,
-				Sha256:      checksumService.getChecksum(releaseAsset.GetBrowserDownloadURL(), releaseAsset.GetName()),
-				Executable:  true,
-			})
-		} else if (strings.Contains(cleanName, "win") || strings.Contains(cleanName, "windows")) && strings.Contains(cleanName, app.Arch) {
-			log.G(ctx).Debugf(" - windows asset %s ", *releaseAsset.Name)
-
-			// If we have an archive, we guess then binary in the archive is name.exe
-			// If this is not right, the linting will catch it
-			if strings.Contains(cleanName, "tar") || strings.Contains(cleanName, "zip") {
-				path = "name .. \".exe\""
+		if isDarwinArm64Name(cleanName) {
+			darwinArm64 = true
+		}
+
+		os := classifyOs(cleanName, app.Arch)
+		if os == "" {
+			if isUniversalName(cleanName) {
+				log.G(ctx).Debugf(" - universal darwin asset %s ", *releaseAsset.Name)
+				universal = append(universal, releaseAsset)
 			}
+			continue
+		}
+		log.G(ctx).Debugf(" - %s asset %s ", os, *releaseAsset.Name)
+		candidates[os] = append(candidates[os], releaseAsset)
+	}
 
-			assets = append(assets, models.Asset{
-				Arch:        "amd64",
-				Os:          "windows",
-				AssertName:  assetName,
-				InstallPath: "\"bin\\\\\" .. name .. \".exe\"",
-				Path:        path,
-				Sha256:      checksumService.getChecksum(releaseAsset.GetBrowserDownloadURL(), releaseAsset.GetName()),
-				Executable:  false,
-			})
+	// Per arch builds are preferred over universal builds
+	if _, ok := candidates["darwin"]; !ok && len(universal) > 0 {
+		candidates["darwin"] = universal
+	}
+
+	assets := []models.Asset{}
+	for os, list := range candidates {
+		releaseAsset := selectAsset(list, app.Prefer, app.Avoid)
+		if len(list) > 1 {
+			log.G(ctx).Debugf("Selected %s among %d %s assets", releaseAsset.GetName(), len(list), os)
+		}
+		asset, err := newAsset(app, os, releaseAsset, checksumService)
+		if err != nil {
+			return nil, err
 		}
+		assets = append(assets, asset)
+
+		// A release with a separate arm64 build does not ship universal binaries
+		if os == "darwin" && !darwinArm64 && g.isUniversalAsset(ctx, releaseAsset) {
+			log.G(ctx).Debugf(" - %s is a universal binary, adding darwin/arm64", releaseAsset.GetName())
+			asset.Arch = "arm64"
+			assets = append(assets, asset)
+		}
+	}
+
+	return g.sortAssets(assets), nil
+}
+
+// isUniversalAsset reports whether a darwin asset contains both amd64 and
+// arm64, either by its name or by the header of a bare binary, which is
+// fetched with a range request. Archives are only detected by their name.
+func (g *Github) isUniversalAsset(ctx context.Context, releaseAsset *github.ReleaseAsset) bool {
+	if isUniversalName(strings.ToLower(releaseAsset.GetName())) {
+		return true
+	}
+	if isArchive(releaseAsset.GetName()) {
+		return false
+	}
+	header, err := download.Prefix(ctx, g.GoFish.HTTPClient, releaseAsset.GetBrowserDownloadURL(), machoHeaderSize)
+	if err != nil {
+		log.G(ctx).Debugf("Could not download header of %s: %v", releaseAsset.GetName(), err)
+		return false
 	}
+	return isUniversal(fatArchs(bytes.NewReader(header)))
+}
+
+func newAsset(app models.Application, os string, releaseAsset *github.ReleaseAsset, checksumService *ChecksumService) (models.Asset, error) {
+	cleanName := strings.ToLower(releaseAsset.GetName())
 
-	return g.sortAssets(assets)
+	sha, source, err := checksumService.getChecksum(releaseAsset.GetBrowserDownloadURL(), releaseAsset.GetName())
+	if err != nil {
+		return models.Asset{}, err
+	}
+
+	assetName := strings.Replace(releaseAsset.GetName(), app.Name, "\" .. name .. \"", 1)
+	assetName = strings.Replace(assetName, app.Version, "\" .. version .. \"", 1)
+	path := "name"
+	if !strings.Contains(cleanName, "tar") && !strings.Contains(cleanName, "zip") {
+		path = strings.Replace(releaseAsset.GetName(), app.Name, "name .. \"", 1) + "\""
+		path = strings.Replace(path, app.Version, "\" .. version .. \"", 1)
+	}
+
+	asset := models.Asset{
+		Arch:           "amd64",
+		Os:             os,
+		AssertName:     assetName,
+		InstallPath:    "\"bin/\" .. name",
+		Path:           path,
+		URL:            releaseURL(app, assetName),
+		Sha256:         sha,
+		ChecksumSource: source,
+		Executable:     true,
+	}
+
+	if os == "windows" {
+		// If we have an archive, we guess then binary in the archive is name.exe
+		// If this is not right, the linting will catch it
+		if strings.Contains(cleanName, "tar") || strings.Contains(cleanName, "zip") {
+			asset.Path = "name .. \".exe\""
+		}
+		asset.InstallPath = "\"bin\\\\\" .. name .. \".exe\""
+		asset.Executable = false
+	}
+	return asset, nil
 }
 
 func (g *Github) sortAssets(assets []models.Asset) []models.Asset {
 	sort.Slice(assets, func(i, j int) bool {
+		if assets[i].Os == assets[j].Os {
+			return assets[i].Arch < assets[j].Arch
+		}
 		return assets[i].Os < assets[j].Os
 	})
 	return assets
@@ -0,0 +1,73 @@
+package github
+
+import (
+	"fmt"
+	"io/ioutil"
+
+	"github.com/gofish-bot/gofish-bot/log"
+	"github.com/gofish-bot/gofish-bot/models"
+	"github.com/gofish-bot/gofish-bot/signature"
+	ghApi "github.com/google/go-github/v32/github"
+)
+
+// signedChecksums reports whether the checksums files must be signed
+func (c *ChecksumService) signedChecksums() bool {
+	v := c.application.Verification
+	return v != nil && (v.Target == "" || v.Target == models.VerifyChecksums)
+}
+
+// signedAssets reports whether every asset must be signed
+func (c *ChecksumService) signedAssets() bool {
+	v := c.application.Verification
+	return v != nil && v.Target == models.VerifyAssets
+}
+
+// findSignature finds the release asset holding the signature of assetName
+func (c *ChecksumService) findSignature(assetName string) *ghApi.ReleaseAsset {
+	for _, ext := range signature.Extensions(c.application.Verification.Method) {
+		for _, asset := range c.assets {
+			if asset.GetName() == assetName+ext {
+				return asset
+			}
+		}
+	}
+	return nil
+}
+
+// verifySignature verifies the downloaded asset against its published
+// signature and the locally configured trusted public key
+func (c *ChecksumService) verifySignature(assetName, url string) error {
+	v := c.application.Verification
+
+	key, err := ioutil.ReadFile(v.Key)
+	if err != nil {
+		return fmt.Errorf("could not read trusted key for %s: %v", c.application.Name, err)
+	}
+
+	sigAsset := c.findSignature(assetName)
+	if sigAsset == nil {
+		return fmt.Errorf("no %s signature found for %s", v.Method, assetName)
+	}
+	sigReader, err := c.downloadFile(sigAsset.GetBrowserDownloadURL())
+	if err != nil {
+		return fmt.Errorf("could not download signature %s: %v", sigAsset.GetName(), err)
+	}
+	defer sigReader.Close()
+	sig, err := ioutil.ReadAll(sigReader)
+	if err != nil {
+		return err
+	}
+
+	content, err := c.downloadFile(url)
+	if err != nil {
+		return err
+	}
+	defer content.Close()
+
+	err = signature.Verify(v.Method, key, content, sig)
+	if err != nil {
+		return fmt.Errorf("signature verification of %s failed: %v", assetName, err)
+	}
+	log.L.Debugf("Verified %s signature of %s", v.Method, assetName)
+	return nil
+}
@@ -0,0 +1,82 @@
+package github
+
+import (
+	"debug/macho"
+	"encoding/binary"
+	"io"
+	"strings"
+
+	"github.com/mholt/archiver/v3"
+)
+
+// universalNames are fragments used in the names of macOS universal assets
+var universalNames = []string{"darwin_all", "darwin-all", "darwin_universal", "darwin-universal", "macos-universal", "macos_universal", "universal"}
+
+// isUniversalName reports whether the lower case asset name marks a macOS
+// universal binary, shipping both amd64 and arm64 in one asset. The name must
+// name macOS as well, a bare universal fragment is used by other platforms.
+func isUniversalName(cleanName string) bool {
+	if !strings.Contains(cleanName, "darwin") && !strings.Contains(cleanName, "mac") && !strings.Contains(cleanName, "osx") {
+		return false
+	}
+	for _, u := range universalNames {
+		if strings.Contains(cleanName, u) {
+			return true
+		}
+	}
+	return false
+}
+
+// machoHeaderSize is the number of bytes read to find the fat header and the
+// architectures of a bare binary
+const machoHeaderSize = 4096
+
+// isDarwinArm64Name reports whether the lower case asset name marks a build
+// for darwin/arm64 only
+func isDarwinArm64Name(cleanName string) bool {
+	if !strings.Contains(cleanName, "darwin") && !strings.Contains(cleanName, "mac") && !strings.Contains(cleanName, "osx") {
+		return false
+	}
+	return strings.Contains(cleanName, "arm64") || strings.Contains(cleanName, "aarch64")
+}
+
+// isArchive reports whether the asset name is an archive format, which can
+// not be inspected without downloading it completely
+func isArchive(name string) bool {
+	_, err := archiver.ByExtension(name)
+	return err == nil
+}
+
+// fatArchs reads the header of a Mach-O universal binary and returns the cpu
+// types it contains, or nil if the content is not a universal binary
+func fatArchs(r io.Reader) []macho.Cpu {
+	var header [2]uint32
+	if err := binary.Read(r, binary.BigEndian, &header); err != nil {
+		return nil
+	}
+	// Java class files share the magic number, but their version is always
+	// larger than any sane number of architectures
+	if header[0] != macho.MagicFat || header[1] == 0 || header[1] > 20 {
+		return nil
+	}
+
+	cpus := []macho.Cpu{}
+	for i := uint32(0); i < header[1]; i++ {
+		var arch macho.FatArchHeader
+		if err := binary.Read(r, binary.BigEndian, &arch); err != nil {
+			return nil
+		}
+		cpus = append(cpus, arch.Cpu)
+	}
+	return cpus
+}
+
+// isUniversal reports whether the cpu types contains both amd64 and arm64
+func isUniversal(cpus []macho.Cpu) bool {
+	amd64, arm64 := false, false
+	for _, cpu := range cpus {
+		amd64 = amd64 || cpu == macho.CpuAmd64
+		arm64 = arm64 || cpu == macho.CpuArm64
+	}
+	return amd64 && arm64
+}
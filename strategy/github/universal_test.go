@@ -0,0 +1,128 @@
+package github
+
+import (
+	"bytes"
+	"context"
+	"debug/macho"
+	"encoding/binary"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	ghApi "github.com/google/go-github/v32/github"
+
+	"github.com/gofish-bot/gofish-bot/gofishgithub"
+)
+
+func Test_isUniversalName(t *testing.T) {
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{name: "goreleaser_darwin_all.tar.gz", want: true},
+		{name: "tool-macos-universal.zip", want: true},
+		{name: "tool_darwin_universal", want: true},
+		{name: "tool-universal-apple-darwin.tar.gz", want: true},
+		{name: "tool-universal.zip", want: false},
+		{name: "tool_universal_linux.tar.gz", want: false},
+		{name: "universal-ctags_windows_amd64.zip", want: false},
+		{name: "tool_darwin_amd64.tar.gz", want: false},
+		{name: "tool_linux_amd64.tar.gz", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isUniversalName(tt.name); got != tt.want {
+				t.Errorf("isUniversalName() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_isDarwinArm64Name(t *testing.T) {
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{name: "tool_darwin_arm64.tar.gz", want: true},
+		{name: "tool-aarch64-apple-darwin.tar.gz", want: true},
+		{name: "tool_macos_arm64", want: true},
+		{name: "tool_darwin_amd64.tar.gz", want: false},
+		{name: "tool_linux_arm64.tar.gz", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isDarwinArm64Name(tt.name); got != tt.want {
+				t.Errorf("isDarwinArm64Name() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func fatHeader(magic uint32, cpus ...macho.Cpu) []byte {
+	var b bytes.Buffer
+	binary.Write(&b, binary.BigEndian, []uint32{magic, uint32(len(cpus))})
+	for _, cpu := range cpus {
+		binary.Write(&b, binary.BigEndian, macho.FatArchHeader{Cpu: cpu})
+	}
+	return b.Bytes()
+}
+
+func Test_fatArchs(t *testing.T) {
+	tests := []struct {
+		name    string
+		content []byte
+		want    bool
+	}{
+		{name: "universal", content: fatHeader(macho.MagicFat, macho.CpuAmd64, macho.CpuArm64), want: true},
+		{name: "fat without arm64", content: fatHeader(macho.MagicFat, macho.CpuAmd64, macho.Cpu386), want: false},
+		{name: "thin binary", content: fatHeader(macho.Magic64, macho.CpuAmd64), want: false},
+		{name: "java class", content: []byte{0xca, 0xfe, 0xba, 0xbe, 0x00, 0x00, 0x00, 0x34}, want: false},
+		{name: "truncated", content: []byte{0xca, 0xfe}, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isUniversal(fatArchs(bytes.NewReader(tt.content))); got != tt.want {
+				t.Errorf("isUniversal(fatArchs()) = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_isUniversalAsset(t *testing.T) {
+	content := append(fatHeader(macho.MagicFat, macho.CpuAmd64, macho.CpuArm64), make([]byte, 2*machoHeaderSize)...)
+	var ranges []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ranges = append(ranges, r.Header.Get("Range"))
+		http.ServeContent(w, r, "tool", time.Time{}, bytes.NewReader(content))
+	}))
+	defer server.Close()
+
+	g := &Github{GoFish: &gofishgithub.GoFish{HTTPClient: server.Client()}}
+	tests := []struct {
+		name       string
+		want       bool
+		wantRanges []string
+	}{
+		{name: "tool_darwin_amd64", want: true, wantRanges: []string{"bytes=0-4095"}},
+		// Archives are never downloaded to look inside
+		{name: "tool_darwin_amd64.tar.gz", want: false},
+		{name: "tool_darwin_all.tar.gz", want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ranges = nil
+			asset := &ghApi.ReleaseAsset{
+				Name:               ghApi.String(tt.name),
+				BrowserDownloadURL: ghApi.String(server.URL + "/" + tt.name),
+			}
+			if got := g.isUniversalAsset(context.Background(), asset); got != tt.want {
+				t.Errorf("isUniversalAsset() = %v, want %v", got, tt.want)
+			}
+			if strings.Join(ranges, ",") != strings.Join(tt.wantRanges, ",") {
+				t.Errorf("isUniversalAsset() requested ranges %v, want %v", ranges, tt.wantRanges)
+			}
+		})
+	}
+}
@@ -0,0 +1,147 @@
+package github
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/gofish-bot/gofish-bot/download"
+	"github.com/gofish-bot/gofish-bot/log"
+	"github.com/gofish-bot/gofish-bot/models"
+)
+
+// platforms are the os/arch combinations probed when the assets of an
+// application are resolved from a download URL template instead of the
+// GitHub release assets.
+var platforms = []struct {
+	Os   string
+	Arch string
+}{
+	{Os: "darwin", Arch: "amd64"},
+	{Os: "linux", Arch: "amd64"},
+	{Os: "windows", Arch: "amd64"},
+}
+
+var placeholderRe = regexp.MustCompile(`{(name|version|release|os|arch|ext)}`)
+
+// releaseURL returns the lua expression for an asset attached to a GitHub release
+func releaseURL(app models.Application, assetName string) string {
+	if app.Name != app.Repo {
+		return fmt.Sprintf("\"https://github.com/%s/%s/releases/download/\" .. release .. \"/%s\"", app.Organization, app.Repo, assetName)
+	}
+	return fmt.Sprintf("\"https://github.com/%s/\" .. name .. \"/releases/download/\" .. release .. \"/%s\"", app.Organization, assetName)
+}
+
+// renderURL expands the {name}, {version}, {release}, {os}, {arch} and {ext}
+// placeholders of a download URL template
+func renderURL(tpl string, app models.Application, os, arch string) string {
+	return placeholderRe.ReplaceAllStringFunc(tpl, func(placeholder string) string {
+		switch placeholder {
+		case "{name}":
+			return app.Name
+		case "{version}":
+			return app.Version
+		case "{release}":
+			return app.ReleaseName
+		}
+		return platformValue(placeholder, os, arch)
+	})
+}
+
+// luaURL converts a download URL template to a lua expression, keeping name,
+// version and release as references to the local variables of the food
+func luaURL(tpl, os, arch string) string {
+	parts := []string{}
+	literal := ""
+	last := 0
+	for _, loc := range placeholderRe.FindAllStringIndex(tpl, -1) {
+		literal += tpl[last:loc[0]]
+		last = loc[1]
+
+		placeholder := tpl[loc[0]:loc[1]]
+		switch placeholder {
+		case "{name}", "{version}", "{release}":
+			if literal != "" {
+				parts = append(parts, fmt.Sprintf("%q", literal))
+				literal = ""
+			}
+			parts = append(parts, strings.Trim(placeholder, "{}"))
+		default:
+			literal += platformValue(placeholder, os, arch)
+		}
+	}
+	literal += tpl[last:]
+	if literal != "" {
+		parts = append(parts, fmt.Sprintf("%q", literal))
+	}
+	return strings.Join(parts, " .. ")
+}
+
+func platformValue(placeholder, os, arch string) string {
+	switch placeholder {
+	case "{os}":
+		return os
+	case "{arch}":
+		return arch
+	case "{ext}":
+		if os == "windows" {
+			return ".exe"
+		}
+	}
+	return ""
+}
+
+// GetTemplatedAssets resolves one asset per platform from the download URL
+// template of the application. GitHub is only used for finding the release,
+// the assets are downloaded and hashed from the host in the template.
+// Platforms without a download are skipped, any other error is returned.
+func (g *Github) GetTemplatedAssets(app models.Application, checksumService *ChecksumService) ([]models.Asset, error) {
+	assets := []models.Asset{}
+
+	arch := app.Arch
+	if arch == "" {
+		arch = "amd64"
+	}
+
+	for _, platform := range platforms {
+		assetURL := renderURL(app.URL, app, platform.Os, arch)
+		fileName := path.Base(assetURL)
+
+		sha, source, err := checksumService.getChecksum(assetURL, fileName)
+		if download.IsNotFound(err) {
+			log.L.Debugf("Skipping %s/%s: %v", platform.Os, platform.Arch, err)
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s/%s: %w", platform.Os, platform.Arch, err)
+		}
+
+		filePath := luaURL(path.Base(app.URL), platform.Os, arch)
+		if strings.Contains(fileName, "tar") || strings.Contains(fileName, "zip") {
+			filePath = "name"
+			if platform.Os == "windows" {
+				filePath = "name .. \".exe\""
+			}
+		}
+
+		asset := models.Asset{
+			Arch:           platform.Arch,
+			Os:             platform.Os,
+			AssertName:     fileName,
+			InstallPath:    "\"bin/\" .. name",
+			Path:           filePath,
+			URL:            luaURL(app.URL, platform.Os, arch),
+			Sha256:         sha,
+			ChecksumSource: source,
+			Executable:     true,
+		}
+		if platform.Os == "windows" {
+			asset.InstallPath = "\"bin\\\\\" .. name .. \".exe\""
+			asset.Executable = false
+		}
+		assets = append(assets, asset)
+	}
+
+	return g.sortAssets(assets), nil
+}
@@ -0,0 +1,112 @@
+package github
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gofish-bot/gofish-bot/cache"
+	"github.com/gofish-bot/gofish-bot/models"
+)
+
+func Test_renderURL(t *testing.T) {
+	app := models.Application{Name: "kubectl", Version: "1.21.2", ReleaseName: "v1.21.2"}
+	tests := []struct {
+		tpl  string
+		os   string
+		arch string
+		want string
+	}{
+		{tpl: "https://dl.k8s.io/release/{release}/bin/{os}/{arch}/{name}{ext}", os: "linux", arch: "amd64", want: "https://dl.k8s.io/release/v1.21.2/bin/linux/amd64/kubectl"},
+		{tpl: "https://dl.k8s.io/release/{release}/bin/{os}/{arch}/{name}{ext}", os: "windows", arch: "amd64", want: "https://dl.k8s.io/release/v1.21.2/bin/windows/amd64/kubectl.exe"},
+		{tpl: "https://releases.hashicorp.com/{name}/{version}/{name}_{version}_{os}_{arch}.zip", os: "darwin", arch: "amd64", want: "https://releases.hashicorp.com/kubectl/1.21.2/kubectl_1.21.2_darwin_amd64.zip"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			if got := renderURL(tt.tpl, app, tt.os, tt.arch); got != tt.want {
+				t.Errorf("renderURL() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_luaURL(t *testing.T) {
+	tests := []struct {
+		tpl  string
+		os   string
+		arch string
+		want string
+	}{
+		{tpl: "https://dl.k8s.io/release/{release}/bin/{os}/{arch}/kubectl{ext}", os: "linux", arch: "amd64", want: `"https://dl.k8s.io/release/" .. release .. "/bin/linux/amd64/kubectl"`},
+		{tpl: "https://dl.k8s.io/release/{release}/bin/{os}/{arch}/kubectl{ext}", os: "windows", arch: "amd64", want: `"https://dl.k8s.io/release/" .. release .. "/bin/windows/amd64/kubectl.exe"`},
+		{tpl: "https://go.dev/dl/go{version}.{os}-{arch}.tar.gz", os: "darwin", arch: "amd64", want: `"https://go.dev/dl/go" .. version .. ".darwin-amd64.tar.gz"`},
+		{tpl: "{name}", os: "darwin", arch: "amd64", want: `name`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			if got := luaURL(tt.tpl, tt.os, tt.arch); got != tt.want {
+				t.Errorf("luaURL() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGithub_GetTemplatedAssets(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gofish-bot-url")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	tests := []struct {
+		name          string
+		windowsStatus int
+		wantOs        []string
+		wantErr       bool
+	}{
+		{name: "all platforms", windowsStatus: http.StatusOK, wantOs: []string{"darwin", "linux", "windows"}},
+		{name: "missing platform", windowsStatus: http.StatusNotFound, wantOs: []string{"darwin", "linux"}},
+		{name: "server error", windowsStatus: http.StatusInternalServerError, wantErr: true},
+		{name: "forbidden", windowsStatus: http.StatusForbidden, wantErr: true},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if strings.Contains(r.URL.Path, "windows") && tt.windowsStatus != http.StatusOK {
+					w.WriteHeader(tt.windowsStatus)
+					return
+				}
+				w.Write([]byte(r.URL.Path))
+			}))
+			defer server.Close()
+
+			c, err := cache.New(filepath.Join(dir, fmt.Sprint(i)), 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			app := models.Application{Name: "tool", Version: "1.0.0", URL: server.URL + "/{version}/{name}_{os}_{arch}{ext}"}
+			service, err := NewChecksumService(app, server.Client(), c, nil, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			g := &Github{}
+			assets, err := g.GetTemplatedAssets(app, service)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GetTemplatedAssets() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			gotOs := []string{}
+			for _, asset := range assets {
+				gotOs = append(gotOs, asset.Os)
+			}
+			if !tt.wantErr && strings.Join(gotOs, " ") != strings.Join(tt.wantOs, " ") {
+				t.Errorf("GetTemplatedAssets() platforms = %v, want %v", gotOs, tt.wantOs)
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package workspace
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// Workspace is the local directory where foods are rendered, linted and read
+// back for the pull request. It mirrors the layout of the fish-food repository.
+type Workspace struct {
+	Dir string
+	// Temporary is set when the workspace was created in the temp dir and
+	// should be removed when the bot is done
+	Temporary bool
+}
+
+// New creates the workspace in dir, or in a new temporary directory when dir
+// is empty
+func New(dir string) (*Workspace, error) {
+	w := &Workspace{Dir: dir}
+	if dir == "" {
+		tmp, err := ioutil.TempDir("", "gofish-bot-workspace")
+		if err != nil {
+			return nil, err
+		}
+		w.Dir = tmp
+		w.Temporary = true
+	}
+	if err := os.MkdirAll(w.foodDir(), os.ModePerm); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+func (w *Workspace) foodDir() string {
+	return filepath.Join(w.Dir, "Food")
+}
+
+// FoodPath returns the path of the food with the given name
+func (w *Workspace) FoodPath(name string) string {
+	return filepath.Join(w.foodDir(), name+".lua")
+}
+
+// WriteFood stores the rendered food
+func (w *Workspace) WriteFood(name string, content []byte) error {
+	return ioutil.WriteFile(w.FoodPath(name), content, 0644)
+}
+
+// ReadFood returns the rendered food
+func (w *Workspace) ReadFood(name string) ([]byte, error) {
+	return ioutil.ReadFile(w.FoodPath(name))
+}
+
+// Close removes the workspace if it is temporary
+func (w *Workspace) Close() error {
+	if !w.Temporary {
+		return nil
+	}
+	return os.RemoveAll(w.Dir)
+}
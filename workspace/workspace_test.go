@@ -0,0 +1,58 @@
+package workspace
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWorkspace(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gofish-bot-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	tests := []struct {
+		name          string
+		dir           string
+		wantTemporary bool
+	}{
+		{name: "configured", dir: filepath.Join(dir, "fish-food"), wantTemporary: false},
+		{name: "temporary", dir: "", wantTemporary: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w, err := New(tt.dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if w.Temporary != tt.wantTemporary {
+				t.Errorf("New() temporary = %v, want %v", w.Temporary, tt.wantTemporary)
+			}
+			if tt.dir != "" && w.FoodPath("tool") != filepath.Join(tt.dir, "Food", "tool.lua") {
+				t.Errorf("FoodPath() = %s", w.FoodPath("tool"))
+			}
+
+			if err := w.WriteFood("tool", []byte("local name = \"tool\"")); err != nil {
+				t.Fatal(err)
+			}
+			content, err := w.ReadFood("tool")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(content) != "local name = \"tool\"" {
+				t.Errorf("ReadFood() = %s", content)
+			}
+
+			if err := w.Close(); err != nil {
+				t.Fatal(err)
+			}
+			_, err = os.Stat(w.Dir)
+			if os.IsNotExist(err) != tt.wantTemporary {
+				t.Errorf("Close() removed = %v, want %v", os.IsNotExist(err), tt.wantTemporary)
+			}
+		})
+	}
+}